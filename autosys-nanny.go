@@ -27,10 +27,18 @@ var (
 	app               = kingpin.New("autosys-nanny", "A command-line tool for managing services defined in yaml configuration file")
 	propertyFile      = app.Flag("config", "YAML file with services properties").Short('c').Required().String()
 	forceRestart      = app.Flag("force-restart", "Restart services even than they already running").Short('r').Bool()
+	dryRun            = app.Flag("dry-run", "Show what would be done without stopping and starting services").Short('n').Bool()
 	listOnly          = app.Flag("list", "Only check services without restart and list them").Short('l').Bool()
 	logFile           = app.Flag("log-file", "Path to log file").Short('f').Default("").String()
-	concurrentWorkers = app.Flag("workers-num", "Maximum number of concurrent workers for processing services").Short('w').Default("100").Int()
+	concurrentWorkers = app.Flag("workers-num", "Maximum number of concurrent workers for reading processes from /proc").Short('w').Default("100").Int()
+	restartWorkers    = app.Flag("restart-workers", "Maximum number of services restarted concurrently").Short('p').Default("10").Int()
+	cgroupRoot        = app.Flag("cgroup-root", "Parent cgroup v2 directory for services with 'cgroup' property").Default(chk.CGROUP_DEFAULT_ROOT).String()
+	stateDir          = app.Flag("state-dir", "Directory for services' states persisted between runs").Default(chk.STATE_DEFAULT_DIR).String()
 	debug             = app.Flag("debug", "Enable debug mode").Short('v').Bool()
+	checkCmd          = app.Command("check", "Check services and restart them if needed").Default()
+	notifyTestCmd     = app.Command("notify-test", "Send test notification by every configured notifier")
+	notifyTestTo      = notifyTestCmd.Flag("to", "Send test email to address instead of general 'mailing_list'. Can be repeated").Strings()
+	command           string
 	supported_os      = []string{"linux"}
 	logger            log.Logger
 )
@@ -61,8 +69,18 @@ func init() {
 		os.Exit(1)
 	}
 
+	// nanny is intermediate process of service started with 'double_fork'
+	if len(os.Args) > 1 && os.Args[1] == chk.SPAWN_EXEC_ARG {
+		chk.SpawnDetached(os.Args[2:])
+	}
+
+	// nanny is started by itself as helper which applies limits to service's process before exec
+	if len(os.Args) > 1 && os.Args[1] == chk.LIMITS_EXEC_ARG {
+		chk.ExecWithLimits(os.Args[2:])
+	}
+
 	app.Version(printVersion())
-	kingpin.MustParse(app.Parse(os.Args[1:]))
+	command = kingpin.MustParse(app.Parse(os.Args[1:]))
 
 	if len(*logFile) == 0 {
 		logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stdout))
@@ -93,7 +111,11 @@ func init() {
 	logger = log.With(logger, "timestamp", log.DefaultTimestamp, "caller", log.DefaultCaller)
 	checker.NewLogger(&logger)
 	checker.ConcurrentWorkers = *concurrentWorkers
+	checker.RestartWorkers = *restartWorkers
 	checker.ForceRestart = *forceRestart
+	checker.DryRun = *dryRun
+	checker.CgroupRoot = *cgroupRoot
+	checker.StateDir = *stateDir
 }
 
 func printCheckerErrorsAndExit(checker *chk.Checker, timeStart time.Time) {
@@ -111,6 +133,18 @@ func main() {
 	timeStart := time.Now()
 
 	checker.PropertiesFilePath, _ = filepath.Abs(*propertyFile)
+	if command == notifyTestCmd.FullCommand() {
+		if err := checker.NotifyTest(*notifyTestTo); err != nil {
+			level.Error(logger).Log("msg", "notification test failed", "error", err.Error())
+
+			os.Exit(1)
+		}
+
+		level.Info(logger).Log("msg", "notification test success", "elapsed_time", time.Since(timeStart))
+
+		os.Exit(0)
+	}
+
 	if *listOnly {
 		if err := checker.List(); err != nil {
 			printCheckerErrorsAndExit(&checker, timeStart)
@@ -0,0 +1,249 @@
+package checker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log/level"
+
+	"github.com/ashokhin/autosys-nanny/pkg/notifier"
+)
+
+const (
+	REMINDER_DEFAULT_INTERVAL time.Duration = time.Hour
+	RATE_LIMIT_DEFAULT_WINDOW time.Duration = time.Hour
+)
+
+// AlertingConfig is 'alerting' section of config
+type AlertingConfig struct {
+	// interval of 'still down' reminders about service which can't be started
+	ReminderInterval time.Duration `yaml:"reminder_interval"`
+	// notifications of service with same state and same kinds of errors are sent once per window
+	DedupWindow time.Duration `yaml:"dedup_window"`
+	// maximum numbers of notifications per service and in total within 'rate_limit_window'
+	ServiceLimit int           `yaml:"service_rate_limit"`
+	GlobalLimit  int           `yaml:"global_rate_limit"`
+	RateWindow   time.Duration `yaml:"rate_limit_window"`
+	// notifications of run are grouped into one message per recipients
+	Digest bool `yaml:"digest"`
+	// notifications which failed to send are retried by next runs within max age
+	OutboxMaxAge time.Duration `yaml:"outbox_max_age"`
+}
+
+func (a *AlertingConfig) String() string {
+	return fmt.Sprintf("%+v", *a)
+}
+
+// alerting returns 'alerting' section or empty one if section isn't set
+func (c *CheckerConfig) alerting() *AlertingConfig {
+	if c.Alerting == nil {
+		return new(AlertingConfig)
+	}
+
+	return c.Alerting
+}
+
+func (c *CheckerConfig) reminderInterval() time.Duration {
+	if c.alerting().ReminderInterval <= 0 {
+		return REMINDER_DEFAULT_INTERVAL
+	}
+
+	return c.Alerting.ReminderInterval
+}
+
+func (c *CheckerConfig) rateWindow() time.Duration {
+	if c.alerting().RateWindow <= 0 {
+		return RATE_LIMIT_DEFAULT_WINDOW
+	}
+
+	return c.Alerting.RateWindow
+}
+
+// transition updates persisted state of service by results of current run.
+// Returns state which should be notified (empty if notification isn't needed)
+// and time since which service was down for 'still-down' and 'recovered' states.
+func (c *Checker) transition(s *Service, now time.Time) (string, time.Time) {
+	prev, ok := c.state.Services[s.ProcessName]
+
+	if !ok {
+		prev = &ServiceState{State: STATE_UP, Since: now}
+	}
+
+	// disabled service isn't expected to be running
+	if s.Disabled {
+		delete(c.state.Services, s.ProcessName)
+
+		if len(s.errorArray) > 0 {
+			return STATE_RESTARTED, time.Time{}
+		}
+
+		return "", time.Time{}
+	}
+
+	failState := s.failState
+
+	// service which was started by previous run is down again, so its restart didn't help
+	if len(failState) == 0 && s.started > 0 && !s.forceRestart && (prev.Restarted || prev.isDown()) {
+		failState = STATE_DOWN
+
+		if prev.isDown() {
+			failState = prev.State
+		}
+
+		err := fmt.Errorf("service '%s' is down again after restart by previous check", s.ProcessName)
+		s.errorArray = append([]*error{&err}, s.errorArray...)
+	}
+
+	if len(failState) == 0 {
+		if prev.isDown() {
+			c.state.Services[s.ProcessName] = &ServiceState{State: STATE_UP, Since: now, Restarts: prev.Restarts}
+
+			return STATE_RECOVERED, prev.Since
+		}
+
+		// running service is confirmed by check without start
+		prev.Restarted = s.started > 0
+		c.state.Services[s.ProcessName] = prev
+
+		if len(s.errorArray) > 0 {
+			return STATE_RESTARTED, time.Time{}
+		}
+
+		return "", time.Time{}
+	}
+
+	if prev.State != failState {
+		state := &ServiceState{State: failState, Since: now, Failures: 1, Restarts: prev.Restarts}
+
+		// service is still down, but for another reason
+		if prev.isDown() {
+			state.Since = prev.Since
+			state.Failures = prev.Failures + 1
+		}
+
+		c.state.Services[s.ProcessName] = state
+
+		return failState, state.Since
+	}
+
+	prev.Failures++
+	c.state.Services[s.ProcessName] = prev
+
+	if now.Sub(prev.LastNotified) < c.Config.reminderInterval() {
+		level.Info(*c.logger).Log("msg", "service is still down. skip notification until reminder",
+			"service", s.ProcessName, "value", prev.LastNotified.Add(c.Config.reminderInterval()))
+
+		return "", prev.Since
+	}
+
+	return STATE_STILL_DOWN, prev.Since
+}
+
+// notified keeps time of delivered notification about down service, so 'still-down' reminders are counted from it.
+// Undelivered notification isn't kept, so reminder is sent by the next run.
+func (c *Checker) notified(service string, now time.Time) {
+	if state, ok := c.state.Services[service]; ok && state.isDown() {
+		state.LastNotified = now
+	}
+}
+
+// countRestarts adds number of instances started in current run to persisted restart count
+func (c *Checker) countRestarts(s *Service) {
+	if state, ok := c.state.Services[s.ProcessName]; ok {
+		state.Restarts += s.started
+	}
+}
+
+// renderSubject replaces subject of message by 'mail_subject_template'.
+// Default subject is kept if template can't be rendered.
+func (c *Checker) renderSubject(msg *notifier.Message) {
+	subject, err := c.templates.Subject(msg)
+
+	if err != nil {
+		level.Warn(*c.logger).Log("msg", "got error when try to render subject template",
+			"service", msg.Service, "error", err.Error())
+
+		c.AllErrorsArray = append(c.AllErrorsArray, &err)
+	}
+
+	msg.Subject = subject
+}
+
+// loadTemplates parses templates from 'general' section
+func (c *Checker) loadTemplates() error {
+	var err error
+
+	m := c.Config.Mailer
+
+	if m == nil {
+		c.templates, err = notifier.NewTemplates("", "", "")
+	} else {
+		c.templates, err = notifier.NewTemplates(m.SubjectTmpl, m.TextTmpl, m.HtmlTmpl)
+	}
+
+	return err
+}
+
+// processInfo returns service's process found by nanny for notification
+func (s *Service) processInfo(now time.Time) *notifier.ProcessInfo {
+	if s.process == nil {
+		return nil
+	}
+
+	return &notifier.ProcessInfo{
+		Pid:       s.process.Pid,
+		PPid:      s.process.PPid,
+		Cmdline:   s.process.Cmdline,
+		StartTime: s.process.ModTime,
+		Uptime:    now.Sub(s.process.ModTime),
+		Rss:       s.process.Rss,
+	}
+}
+
+// serviceMessage returns notification about state of service with errors of current run
+func (c *Checker) serviceMessage(s *Service, state string, since time.Time, now time.Time) *notifier.Message {
+	var subject string
+
+	errors := s.errorArray
+
+	switch state {
+	case STATE_DOWN:
+		subject = fmt.Sprintf("%s | '%s' DOWN - can't be started", c.subjectPrefix, s.ProcessName)
+	case STATE_RESTART_FAILED:
+		subject = fmt.Sprintf("%s | '%s' RESTART FAILED - service is down", c.subjectPrefix, s.ProcessName)
+	case STATE_STILL_DOWN:
+		subject = fmt.Sprintf("%s | '%s' STILL DOWN for %s", c.subjectPrefix, s.ProcessName, notifier.FormatDuration(now.Sub(since)))
+		status := fmt.Errorf("service '%s' is down since %s. Failed checks: %d",
+			s.ProcessName, since.Format(time.RFC3339), c.state.Services[s.ProcessName].Failures)
+		errors = append([]*error{&status}, errors...)
+	case STATE_RECOVERED:
+		subject = fmt.Sprintf("%s | '%s' RECOVERED after %s down", c.subjectPrefix, s.ProcessName, notifier.FormatDuration(now.Sub(since)))
+		status := fmt.Errorf("service '%s' is running again. It was down since %s",
+			s.ProcessName, since.Format(time.RFC3339))
+		errors = append([]*error{&status}, errors...)
+	default:
+		subject = fmt.Sprintf("%s | '%s' alert - restarted", c.subjectPrefix, s.ProcessName)
+	}
+
+	msg := &notifier.Message{
+		Hostname:    c.hostname,
+		Service:     s.ProcessName,
+		Description: s.Description,
+		State:       state,
+		Subject:     subject,
+		Errors:      errors,
+		Time:        now,
+		Process:     s.processInfo(now),
+		LogTail:     s.stderrTail(),
+		ExitStatus:  s.exitStatus,
+		StopOutput:  s.stopOutput,
+	}
+
+	msg.Diagnostics, msg.Attachments = s.diagnostics()
+
+	if st, ok := c.state.Services[s.ProcessName]; ok {
+		msg.RestartCount = st.Restarts
+	}
+
+	return msg
+}
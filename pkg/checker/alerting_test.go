@@ -0,0 +1,145 @@
+package checker
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/ashokhin/autosys-nanny/pkg/notifier"
+)
+
+func newTestChecker() *Checker {
+	logger := log.NewNopLogger()
+
+	return &Checker{
+		Config: &CheckerConfig{Alerting: &AlertingConfig{ReminderInterval: time.Hour}},
+		state:  newStateFile(),
+		logger: &logger,
+	}
+}
+
+// runResult returns service after check with given result
+func runResult(failState string, started int, errs ...string) *Service {
+	s := newTestService("alert-test")
+	s.failState = failState
+	s.started = started
+
+	for _, e := range errs {
+		err := errors.New(e)
+		s.errorArray = append(s.errorArray, &err)
+	}
+
+	return s
+}
+
+func TestTransition(t *testing.T) {
+	c := newTestChecker()
+	down := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	if state, _ := c.transition(runResult("", 0), down.Add(-time.Minute)); state != "" {
+		t.Errorf("running service state = %q, want no notification", state)
+	}
+
+	steps := []struct {
+		name      string
+		s         *Service
+		after     time.Duration
+		delivered bool
+		want      string
+	}{
+		{"failed restart", runResult(STATE_RESTART_FAILED, 0, "start failed"), 0, false, STATE_RESTART_FAILED},
+		// notification about failure wasn't delivered, so reminder isn't delayed
+		{"undelivered failure", runResult(STATE_RESTART_FAILED, 0, "start failed"), time.Minute, true, STATE_STILL_DOWN},
+		{"within reminder", runResult(STATE_RESTART_FAILED, 0, "start failed"), 30 * time.Minute, false, ""},
+		{"reminder", runResult(STATE_RESTART_FAILED, 0, "start failed"), 2 * time.Hour, true, STATE_STILL_DOWN},
+		{"recovered", runResult("", 0), 3 * time.Hour, false, STATE_RECOVERED},
+	}
+
+	for _, step := range steps {
+		now := down.Add(step.after)
+		state, since := c.transition(step.s, now)
+
+		if state != step.want {
+			t.Fatalf("%s: state = %q, want %q", step.name, state, step.want)
+		}
+
+		if (state == STATE_STILL_DOWN || state == STATE_RECOVERED) && !since.Equal(down) {
+			t.Errorf("%s: down since %s, want %s", step.name, since, down)
+		}
+
+		if step.delivered {
+			c.notified(step.s.ProcessName, now)
+		}
+	}
+
+	if state := c.state.Services["alert-test"]; state.State != STATE_UP || state.Failures != 0 {
+		t.Errorf("recovered service state = %+v, want 'up'", state)
+	}
+}
+
+func TestTransitionDownAgainAfterRestart(t *testing.T) {
+	c := newTestChecker()
+	now := time.Now()
+
+	if state, _ := c.transition(runResult("", 1, "service restarted"), now); state != STATE_RESTARTED {
+		t.Fatalf("restarted service state = %q, want %q", state, STATE_RESTARTED)
+	}
+
+	// service started by previous run isn't running again
+	s := runResult("", 1, "service restarted")
+
+	if state, _ := c.transition(s, now.Add(time.Minute)); state != STATE_DOWN {
+		t.Fatalf("service down again after restart state = %q, want %q", state, STATE_DOWN)
+	}
+
+	if !strings.Contains((*s.errorArray[0]).Error(), "down again after restart") {
+		t.Errorf("first error = %q, want explanation of escalation", *s.errorArray[0])
+	}
+
+	// service found running without restart
+	if state, _ := c.transition(runResult("", 0), now.Add(2*time.Minute)); state != STATE_RECOVERED {
+		t.Errorf("running service state = %q, want %q", state, STATE_RECOVERED)
+	}
+}
+
+type testNotifier struct {
+	name     string
+	err      error
+	messages []*notifier.Message
+}
+
+func (n *testNotifier) Name() string {
+	return n.name
+}
+
+func (n *testNotifier) Notify(msg *notifier.Message) error {
+	n.messages = append(n.messages, msg)
+
+	return n.err
+}
+
+func TestNotifyDelivered(t *testing.T) {
+	c := newTestChecker()
+	msg := &notifier.Message{Service: "alert-test", Subject: "alert"}
+	failing := &testNotifier{err: errors.New("connection refused")}
+	working := &testNotifier{}
+
+	if c.notify(msg, []notifier.Notifier{failing}) {
+		t.Error("notify() by failing notifier returns delivered")
+	}
+
+	if !c.notify(msg, []notifier.Notifier{failing, working}) {
+		t.Error("notify() by working notifier returns not delivered")
+	}
+
+	if len(working.messages) != 1 || len(failing.messages) != 2 {
+		t.Errorf("notifiers got %d and %d messages, want 1 and 2", len(working.messages), len(failing.messages))
+	}
+
+	if len(c.AllErrorsArray) != 2 {
+		t.Errorf("errors of notifications = %d, want 2", len(c.AllErrorsArray))
+	}
+}
@@ -0,0 +1,281 @@
+package checker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+const (
+	CGROUP_DEFAULT_ROOT  string        = "/sys/fs/cgroup/nanny"
+	CGROUP2_SUPER_MAGIC  int64         = 0x63677270
+	CGROUP_KILL_TIMEOUT  time.Duration = 5 * time.Second
+	CGROUP_POLL_INTERVAL time.Duration = 50 * time.Millisecond
+)
+
+// characters which can't be used in cgroup name
+var cgroupNameRegexp = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// CgroupConfig is 'cgroup' property of service
+type CgroupConfig struct {
+	Name      string `yaml:"name"`
+	MemoryMax string `yaml:"memory_max"`
+	CpuMax    string `yaml:"cpu_max"`
+}
+
+func (c *CgroupConfig) String() string {
+	return fmt.Sprintf("%+v", *c)
+}
+
+// cgroupName returns 'cgroup.name' or 'process_name' with unsupported characters replaced by '_'
+func (s *Service) cgroupName() string {
+	if len(s.Cgroup.Name) > 0 {
+		return s.Cgroup.Name
+	}
+
+	return strings.Trim(cgroupNameRegexp.ReplaceAllString(s.ProcessName, "_"), "_.")
+}
+
+// checkCgroupName returns error if name can't be used as name of service's own cgroup
+func checkCgroupName(name string) error {
+	if len(name) == 0 || name == "." || name == ".." || cgroupNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid cgroup name '%s'. name should contain only letters, digits, '.', '_', '-' and can't be '.' or '..'", name)
+	}
+
+	return nil
+}
+
+// path of service's cgroup: '<cgroup root>/<cgroup.name or sanitized process_name>'.
+// Returns error instead of cgroup root or path outside of it, so service never removes or kills the whole root.
+func (s *Service) cgroupPath() (string, error) {
+	name := s.cgroupName()
+
+	if err := checkCgroupName(name); err != nil {
+		return "", err
+	}
+
+	if !filepath.IsAbs(s.cgroupRoot) {
+		return "", fmt.Errorf("cgroup root '%s' isn't absolute path", s.cgroupRoot)
+	}
+
+	cgroupPath := filepath.Join(s.cgroupRoot, name)
+
+	if filepath.Dir(cgroupPath) != filepath.Clean(s.cgroupRoot) {
+		return "", fmt.Errorf("cgroup '%s' isn't a child of cgroup root '%s'", cgroupPath, s.cgroupRoot)
+	}
+
+	return cgroupPath, nil
+}
+
+func writeCgroupFile(cgroupPath string, file string, value string) error {
+	return os.WriteFile(filepath.Join(cgroupPath, file), []byte(value), 0644)
+}
+
+// cgroupProcs returns pids of processes in cgroup
+func cgroupProcs(cgroupPath string) ([]int, error) {
+	var pids []int
+
+	f, err := os.Open(filepath.Join(cgroupPath, "cgroup.procs"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		if pid, err := strconv.Atoi(strings.TrimSpace(scanner.Text())); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+
+	return pids, scanner.Err()
+}
+
+// cgroupOOMKills returns 'oom_kill' counter from cgroup's 'memory.events'
+func cgroupOOMKills(cgroupPath string) int {
+	content, err := os.ReadFile(filepath.Join(cgroupPath, "memory.events"))
+
+	if err != nil {
+		return 0
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		if value, ok := strings.CutPrefix(line, "oom_kill "); ok {
+			count, _ := strconv.Atoi(strings.TrimSpace(value))
+
+			return count
+		}
+	}
+
+	return 0
+}
+
+// checkCgroup2 returns error if path isn't on cgroup v2 filesystem
+func checkCgroup2(path string) error {
+	var stat syscall.Statfs_t
+
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return err
+	}
+
+	if int64(stat.Type) != CGROUP2_SUPER_MAGIC {
+		return fmt.Errorf("'%s' isn't cgroup v2 filesystem", path)
+	}
+
+	return nil
+}
+
+// controllers which should be enabled in parent cgroup for service's limits
+func (s *Service) cgroupControllers() []string {
+	var controllers []string
+
+	if len(s.Cgroup.MemoryMax) > 0 {
+		controllers = append(controllers, "+memory")
+	}
+
+	if len(s.Cgroup.CpuMax) > 0 {
+		controllers = append(controllers, "+cpu")
+	}
+
+	return controllers
+}
+
+// oomRestartReason returns restart reason if service's processes were killed by OOM killer
+func (s *Service) oomRestartReason() string {
+	if s.Cgroup == nil {
+		return ""
+	}
+
+	cgroupPath, err := s.cgroupPath()
+
+	if err != nil {
+		return ""
+	}
+
+	if count := cgroupOOMKills(cgroupPath); count > 0 {
+		return fmt.Sprintf("killed by OOM killer: oom_kill %d in cgroup '%s'", count, cgroupPath)
+	}
+
+	return ""
+}
+
+// setupCgroup creates service's cgroup, sets its limits and returns opened cgroup directory.
+// Empty cgroup is recreated for resetting 'memory.events' counters.
+func (s *Service) setupCgroup() (*os.File, error) {
+	cgroupPath, err := s.cgroupPath()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkCgroup2(filepath.Dir(s.cgroupRoot)); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(s.cgroupRoot, 0755); err != nil {
+		return nil, err
+	}
+
+	if controllers := s.cgroupControllers(); len(controllers) > 0 {
+		for _, parent := range []string{filepath.Dir(s.cgroupRoot), s.cgroupRoot} {
+			if err := writeCgroupFile(parent, "cgroup.subtree_control", strings.Join(controllers, " ")); err != nil {
+				return nil, fmt.Errorf("can't enable controllers %v in '%s': %w", controllers, parent, err)
+			}
+		}
+	}
+
+	if pids, err := cgroupProcs(cgroupPath); err == nil && len(pids) == 0 {
+		level.Debug(*s.Logger).Log("msg", "recreate empty cgroup", "service", s.ProcessName, "value", cgroupPath)
+
+		if err := syscall.Rmdir(cgroupPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.Mkdir(cgroupPath, 0755); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+
+	if len(s.Cgroup.MemoryMax) > 0 {
+		if err := writeCgroupFile(cgroupPath, "memory.max", s.Cgroup.MemoryMax); err != nil {
+			return nil, fmt.Errorf("can't set 'memory.max' '%s': %w", s.Cgroup.MemoryMax, err)
+		}
+	}
+
+	if len(s.Cgroup.CpuMax) > 0 {
+		if err := writeCgroupFile(cgroupPath, "cpu.max", s.Cgroup.CpuMax); err != nil {
+			return nil, fmt.Errorf("can't set 'cpu.max' '%s': %w", s.Cgroup.CpuMax, err)
+		}
+	}
+
+	return os.Open(cgroupPath)
+}
+
+// moveToCgroup moves launched process into service's cgroup, only children forked later stay in it
+func (s *Service) moveToCgroup(pid int) error {
+	cgroupPath, err := s.cgroupPath()
+
+	if err != nil {
+		return err
+	}
+
+	level.Debug(*s.Logger).Log("msg", "move process to cgroup", "service", s.ProcessName,
+		"pid", pid, "value", cgroupPath)
+
+	return writeCgroupFile(cgroupPath, "cgroup.procs", strconv.Itoa(pid))
+}
+
+// killCgroup kills all processes of service's cgroup and waits until cgroup is empty
+func (s *Service) killCgroup() error {
+	cgroupPath, err := s.cgroupPath()
+
+	if err != nil {
+		return err
+	}
+
+	level.Debug(*s.Logger).Log("msg", "kill all processes in cgroup", "service", s.ProcessName,
+		"value", cgroupPath)
+
+	// 'cgroup.kill' is available since linux 5.14
+	useCgroupKill := writeCgroupFile(cgroupPath, "cgroup.kill", "1") == nil
+	deadline := time.Now().Add(CGROUP_KILL_TIMEOUT)
+
+	for {
+		pids, err := cgroupProcs(cgroupPath)
+
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if len(pids) == 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("processes %v are still alive in cgroup '%s' after %s", pids, cgroupPath, CGROUP_KILL_TIMEOUT)
+		}
+
+		if !useCgroupKill {
+			for _, pid := range pids {
+				syscall.Kill(pid, syscall.SIGKILL)
+			}
+		}
+
+		time.Sleep(CGROUP_POLL_INTERVAL)
+	}
+}
@@ -0,0 +1,61 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestCgroupPath(t *testing.T) {
+	tests := []struct {
+		processName string
+		name        string
+		root        string
+		want        string
+	}{
+		{processName: "python3 worker.py --id=1", root: "/sys/fs/cgroup/nanny", want: "/sys/fs/cgroup/nanny/python3_worker.py_--id_1"},
+		{processName: "/opt/app/bin/app", root: "/sys/fs/cgroup/nanny/", want: "/sys/fs/cgroup/nanny/opt_app_bin_app"},
+		{processName: "...", name: "app", root: "/sys/fs/cgroup/nanny", want: "/sys/fs/cgroup/nanny/app"},
+		{processName: "...", root: "/sys/fs/cgroup/nanny"},
+		{processName: "/", root: "/sys/fs/cgroup/nanny"},
+		{processName: "app", name: "..", root: "/sys/fs/cgroup/nanny"},
+		{processName: "app", name: "../other", root: "/sys/fs/cgroup/nanny"},
+		{processName: "app", root: "nanny"},
+	}
+
+	for _, tt := range tests {
+		s := newTestService(tt.processName)
+		s.Cgroup = &CgroupConfig{Name: tt.name}
+		s.cgroupRoot = tt.root
+
+		got, err := s.cgroupPath()
+
+		switch {
+		case len(tt.want) == 0 && err == nil:
+			t.Errorf("cgroupPath() of '%s' with name '%s' in '%s' = %q, want error", tt.processName, tt.name, tt.root, got)
+		case len(tt.want) > 0 && err != nil:
+			t.Errorf("cgroupPath() of '%s' unexpected error: %v", tt.processName, err)
+		case got != tt.want:
+			t.Errorf("cgroupPath() of '%s' = %q, want %q", tt.processName, got, tt.want)
+		}
+	}
+}
+
+func TestServiceCgroupNameValidation(t *testing.T) {
+	var config CheckerConfig
+
+	input := "services_list:\n  - process_name: \"...\"\n    start_cmd: \"./run\"\n    cgroup:\n      memory_max: \"512M\"\n"
+
+	err := yaml.Unmarshal([]byte(input), &config)
+
+	if err == nil || !strings.Contains(err.Error(), "invalid cgroup name") {
+		t.Fatalf("Unmarshal() error = %v, want invalid cgroup name", err)
+	}
+
+	input = strings.Replace(input, "memory_max", "name: \"app\"\n      memory_max", 1)
+
+	if err := yaml.Unmarshal([]byte(input), &config); err != nil {
+		t.Fatalf("Unmarshal() with explicit cgroup name unexpected error: %v", err)
+	}
+}
@@ -2,9 +2,12 @@ package checker
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -16,21 +19,43 @@ import (
 
 	npf "github.com/ashokhin/autosys-nanny/pkg/file"
 	"github.com/ashokhin/autosys-nanny/pkg/mailer"
+	"github.com/ashokhin/autosys-nanny/pkg/notifier"
 )
 
 type Checker struct {
 	PropertiesFilePath string
 	Config             *CheckerConfig
 	ConcurrentWorkers  int
+	RestartWorkers     int
 	ForceRestart       bool
+	DryRun             bool
+	CgroupRoot         string
+	StateDir           string
 	checkerErrorArray  []*error
 	AllErrorsArray     []*error
 	hostname           string
+	subjectPrefix      string
+	notifiers          map[string]notifier.Notifier
+	templates          *notifier.Templates
+	digests            map[string]*digestGroup
+	digestKeys         []string
+	state              *stateFile
 	logger             *log.Logger
 }
 
 var processesList map[int]*Process
 
+// values at these paths aren't expanded on load: 'env_vars' are interpolated with service's environment
+// right before start, commands are passed to shell as is, so shell syntax like '${VAR:-default}' works
+var rawConfigPaths = []string{
+	"services_list.env_vars",
+	"services_list.start_cmd",
+	"services_list.cmd_args",
+	"services_list.stop_cmd",
+	"services_list.hooks",
+	"services_list.diagnostics",
+}
+
 func (c *Checker) String() string {
 	return fmt.Sprintf("%+v", *c)
 }
@@ -41,13 +66,34 @@ func (c *Checker) loadYaml() error {
 
 	level.Debug(*c.logger).Log("msg", "load yaml file", "value", c.PropertiesFilePath)
 
-	if err := npf.LoadYamlFile(c.PropertiesFilePath, &c.Config, *c.logger); err != nil {
+	if err := npf.LoadYamlFile(c.PropertiesFilePath, &c.Config, *c.logger, rawConfigPaths...); err != nil {
 		level.Error(*c.logger).Log("msg", "error loading yaml file",
 			"value", c.PropertiesFilePath, "error", err.Error())
 
 		return err
 	}
 
+	if err := c.Config.checkDependencies(); err != nil {
+		level.Error(*c.logger).Log("msg", "error in services dependencies",
+			"value", c.PropertiesFilePath, "error", err.Error())
+
+		return err
+	}
+
+	if err := c.Config.checkNotifiers(); err != nil {
+		level.Error(*c.logger).Log("msg", "error in notifiers",
+			"value", c.PropertiesFilePath, "error", err.Error())
+
+		return err
+	}
+
+	if err := c.loadTemplates(); err != nil {
+		level.Error(*c.logger).Log("msg", "error in templates",
+			"value", c.PropertiesFilePath, "error", err.Error())
+
+		return err
+	}
+
 	if c.Config.Mailer != nil {
 		c.Config.Mailer.SafeStorePassword()
 	}
@@ -111,6 +157,13 @@ func (c *Checker) getProcessInfo(workerId int, chProcPath <-chan string, chResul
 					level.Error(*c.logger).Log("msg", "can't convert ppid string to Int",
 						"worker", workerId, "value", processPPidStr, "error", err.Error())
 				}
+			case strings.HasPrefix(line, "VmRSS:"):
+				// value in kB, absent for kernel threads
+				rssStr, _ := strings.CutPrefix(line, "VmRSS:")
+
+				if rss, err := strconv.ParseInt(strings.TrimSuffix(strings.Trim(rssStr, "\t "), " kB"), 10, 64); err == nil {
+					process.Rss = rss * 1024
+				}
 			}
 		}
 
@@ -182,6 +235,8 @@ ReadResults:
 		}
 	}
 
+	buildProcessesTree()
+
 	if len(matches) != len(processesList) {
 		level.Debug(*c.logger).Log("msg", "len(matches) != len(c.processes)",
 			"matches", len(matches), "processes", len(processesList))
@@ -190,6 +245,8 @@ ReadResults:
 
 // if service pid found than return `true` otherwise return `false`
 func (c *Checker) searchServicePid(service *Service) bool {
+	var matches []*Process
+
 	level.Debug(*c.logger).Log("msg", "search service pid",
 		"value", service.ProcessName)
 
@@ -199,18 +256,29 @@ func (c *Checker) searchServicePid(service *Service) bool {
 			level.Debug(*c.logger).Log("msg", "service pid found in process list",
 				"service", service.ProcessName, "value", pid)
 
-			if service.process != nil {
-				// search main pid (always lower number in process tree)
-				if service.process.Pid > p.Pid {
-					service.process = p
-				}
-			} else {
-				service.process = p
-			}
+			matches = append(matches, p)
 		}
 	}
 
-	return service.process != nil
+	for _, p := range matches {
+		// service's instances are topmost matching processes in process tree
+		if slices.ContainsFunc(matches, func(ancestor *Process) bool { return isDescendant(p.Pid, ancestor.Pid) }) {
+			continue
+		}
+
+		service.instances = append(service.instances, p)
+	}
+
+	if len(service.instances) == 0 {
+		return false
+	}
+
+	slices.SortFunc(service.instances, func(a, b *Process) int { return a.Pid - b.Pid })
+
+	// main process is the oldest instance with the lowest pid
+	service.process = service.instances[0]
+
+	return true
 }
 
 func (c *Checker) checkService(service *Service, wg *sync.WaitGroup) error {
@@ -292,7 +360,7 @@ func (c *Checker) List() error {
 
 	// create tabWriter output filter
 	w := tabwriter.NewWriter(os.Stdout, 4, 4, 4, ' ', tabwriter.TabIndent|tabwriter.Debug)
-	fmt.Fprintln(w, "Service\tRunning\tDisabled\tPID\tStartTime\tUptime\tCmdLine")
+	fmt.Fprintln(w, "Service\tRunning\tInstances\tDisabled\tPID\tStartTime\tUptime\tLimits\tCmdLine")
 	for _, s := range c.Config.Services {
 		s.Logger = c.logger
 
@@ -302,12 +370,12 @@ func (c *Checker) List() error {
 		}
 
 		if s.process != nil {
-			fmt.Fprintf(w, "%s\t%t\t%t\t%d\t%s\t%s\t%s\n", s.ProcessName,
-				(s.process != nil), s.Disabled, s.process.Pid, s.process.ModTime,
-				time.Since(s.process.ModTime), s.process.Cmdline)
+			fmt.Fprintf(w, "%s\t%t\t%d/%d\t%t\t%d\t%s\t%s\t%s\t%s\n", s.ProcessName,
+				(s.process != nil), len(s.instances), s.instancesCount(), s.Disabled, s.process.Pid, s.process.ModTime,
+				time.Since(s.process.ModTime), s.effectiveLimits(s.process.Pid), s.process.Cmdline)
 		} else {
-			fmt.Fprintf(w, "%s\t%t\t%t\t%d\t%s\t%s\t%s\n", s.ProcessName,
-				(s.process != nil), s.Disabled, 0, "null", "null", "null")
+			fmt.Fprintf(w, "%s\t%t\t%d/%d\t%t\t%d\t%s\t%s\t%s\t%s\n", s.ProcessName,
+				(s.process != nil), 0, s.instancesCount(), s.Disabled, 0, "null", "null", "null", "null")
 		}
 	}
 	w.Flush()
@@ -324,47 +392,197 @@ func (c *Checker) CheckAndRestart() error {
 		return err
 	}
 
-	for _, service := range c.Config.Services {
+	var wg sync.WaitGroup
+
+	byName := c.Config.servicesByName()
+	// closed when service's check and restart is done, so its dependents can proceed
+	done := make([]chan struct{}, len(c.Config.Services))
+	workers := c.RestartWorkers
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	// limits number of concurrently restarted services
+	chWorkers := make(chan struct{}, workers)
+
+	for i := range c.Config.Services {
+		done[i] = make(chan struct{})
+	}
+
+	for i, service := range c.Config.Services {
 		service.Logger = c.logger
+		service.cgroupRoot = c.CgroupRoot
 
 		// skip empty service
 		if len(service.ProcessName) == 0 {
+			close(done[i])
+
 			continue
 		}
 
-		if (service.process == nil) || c.ForceRestart {
-			service.RestartProcess(c.ForceRestart)
-		}
+		wg.Add(1)
 
-		if (service.process != nil) && (service.Disabled) {
-			service.RestartProcess(c.ForceRestart)
-		}
+		go func(i int, service *Service) {
+			defer wg.Done()
+			defer close(done[i])
+
+			for _, name := range service.DependsOn {
+				for _, dep := range byName[name] {
+					level.Debug(*c.logger).Log("msg", "wait for dependency", "service", service.ProcessName,
+						"value", name)
+
+					<-done[dep]
+				}
+			}
+
+			chWorkers <- struct{}{}
+			defer func() { <-chWorkers }()
+
+			c.checkAndRestartService(service)
+		}(i, service)
 	}
 
+	wg.Wait()
+
 	return nil
 }
 
-func (c *Checker) ReportErrors() bool {
-	var gotErrors bool
+// checkAndRestartService restarts service if it's needed within 'restart_timeout'
+func (c *Checker) checkAndRestartService(service *Service) {
+	ctx, cancel := context.WithTimeout(context.Background(), service.restartTimeout())
+	defer cancel()
+
+	service.ctx = ctx
+
+	if !c.DryRun {
+		service.rotateLogs()
+	}
+
+	if (service.process == nil) || c.ForceRestart {
+		service.RestartProcess(c.ForceRestart, c.DryRun)
+
+		return
+	}
+
+	if service.Disabled {
+		service.RestartProcess(c.ForceRestart, c.DryRun)
+
+		return
+	}
 
-	subjectPrefix := strings.ToUpper(c.hostname)
+	service.CheckInstances(c.DryRun)
+}
+
+// setupNotifiers creates notifiers from 'notifiers' section and mailer from 'general' section
+func (c *Checker) setupNotifiers() {
+	c.subjectPrefix = strings.ToUpper(c.hostname)
 
 	if c.Config.Mailer != nil {
 		c.Config.Mailer.Logger = c.logger
 
-		if c.Config.Mailer.Headers != nil {
-			// save mailing_list form YAML key 'general.mailing_list' before processing services
-			c.Config.to = c.Config.Mailer.Headers.To
-		}
-
 		if len(c.Config.Mailer.SubjectPrefix) > 0 {
-			subjectPrefix = c.Config.Mailer.SubjectPrefix
+			c.subjectPrefix = c.Config.Mailer.SubjectPrefix
 		}
 	} else {
 		c.Config.Mailer = new(mailer.Mailer)
+		c.Config.Mailer.Logger = c.logger
+	}
+
+	c.notifiers = make(map[string]notifier.Notifier)
+	c.digests = make(map[string]*digestGroup)
+
+	for _, config := range c.Config.Notifiers {
+		c.notifiers[config.Name] = notifier.New(config, c.Config.Mailer, c.templates, c.logger)
+	}
+}
+
+// notifiers of service: email to service's 'mailing_list' and named 'notifiers'
+func (c *Checker) serviceNotifiers(s *Service) []notifier.Notifier {
+	var notifiers []notifier.Notifier
+
+	if len(s.MailList) > 0 {
+		notifiers = append(notifiers, notifier.NewSmtp(notifier.MAILING_LIST_NAME, c.Config.Mailer, c.templates, s.MailList))
+	}
+
+	for _, name := range s.Notifiers {
+		notifiers = append(notifiers, c.notifiers[name])
+	}
+
+	return notifiers
+}
+
+// notifiers of nanny's own errors: email to general 'mailing_list' and notifiers with 'nanny_errors'
+func (c *Checker) nannyNotifiers() []notifier.Notifier {
+	var notifiers []notifier.Notifier
+
+	if c.Config.Mailer.Headers != nil && len(c.Config.Mailer.Headers.To) > 0 {
+		notifiers = append(notifiers, notifier.NewSmtp(notifier.MAILING_LIST_NAME, c.Config.Mailer, c.templates, c.Config.Mailer.Headers.To))
+	}
+
+	for _, config := range c.Config.Notifiers {
+		if config.NannyErrors {
+			notifiers = append(notifiers, c.notifiers[config.Name])
+		}
+	}
+
+	return notifiers
+}
+
+// notify sends message by notifiers and returns true if at least one of them delivered it.
+// Errors of notifiers are added into c.AllErrorsArray, undelivered message is stored in outbox.
+func (c *Checker) notify(msg *notifier.Message, notifiers []notifier.Notifier) bool {
+	var delivered bool
+
+	for _, n := range notifiers {
+		level.Debug(*c.logger).Log("msg", "send notification", "notifier", n.Name(), "value", msg.Subject)
+
+		if err := n.Notify(msg); err != nil {
+			level.Warn(*c.logger).Log("msg", "got error when try to send notification",
+				"notifier", n.Name(), "service", msg.Service, "error", err.Error())
+
+			c.AllErrorsArray = append(c.AllErrorsArray, &err)
+
+			// errors of settings and templates aren't fixed by retry
+			var errNotify *notifier.ErrNotify
+
+			if errors.As(err, &errNotify) {
+				c.spool(msg, n, err)
+			}
+
+			continue
+		}
+
+		delivered = true
+	}
+
+	return delivered
+}
+
+func (c *Checker) ReportErrors() bool {
+	var gotErrors bool
+
+	c.setupNotifiers()
+
+	now := time.Now()
+
+	// state isn't changed by dry run
+	if !c.DryRun {
+		if err := c.loadState(); err != nil {
+			level.Warn(*c.logger).Log("msg", "got error when try to load state file",
+				"value", c.statePath(), "error", err.Error())
+
+			err1 := fmt.Errorf("'Nanny' script error: %s", err.Error())
+			c.checkerErrorArray = append(c.checkerErrorArray, &err1)
+		}
+
+		// notifications which failed to send by previous runs
+		c.retryOutbox(now)
 	}
 
 	for _, s := range c.Config.Services {
+		var state string
+		var since time.Time
 
 		// report about errors in services
 		if len(s.errorArray) > 0 {
@@ -374,30 +592,43 @@ func (c *Checker) ReportErrors() bool {
 			}
 			// add service's errors to global array
 			c.AllErrorsArray = append(c.AllErrorsArray, s.errorArray...)
+		}
 
-			if len(s.MailList) == 0 {
-				level.Debug(*c.logger).Log("msg", "service doesn't have 'mailing_list'. skip sending emails",
-					"service", s.ProcessName)
-
-				continue
+		if c.DryRun {
+			if len(s.errorArray) > 0 {
+				state = STATE_RESTARTED
 			}
+		} else if len(s.ProcessName) > 0 {
+			state, since = c.transition(s, now)
+			c.countRestarts(s)
+		}
 
-			if err := c.Config.Mailer.CheckSettings(); err != nil {
-				level.Warn(*c.logger).Log("msg", "checker mail config inconsistent. skip sending emails",
-					"service", s.ProcessName, "error", err)
+		if len(state) > 0 {
+			level.Info(*c.logger).Log("msg", "service state changed", "service", s.ProcessName, "value", state)
+		}
 
-				c.AllErrorsArray = append(c.AllErrorsArray, &err)
+		notifiers := c.serviceNotifiers(s)
 
-				continue
+		if len(notifiers) == 0 {
+			if len(state) > 0 {
+				level.Debug(*c.logger).Log("msg", "service doesn't have 'mailing_list' or 'notifiers'. skip sending notifications",
+					"service", s.ProcessName)
 			}
 
-			c.Config.Mailer.Headers.To = s.MailList
-
-			c.Config.Mailer.Headers.Subject = fmt.Sprintf("%s | '%s' alert - restarted", subjectPrefix, s.ProcessName)
+			continue
+		}
 
-			if err := c.Config.Mailer.SendHtmlEmail(s.errorArray); err != nil {
-				c.AllErrorsArray = append(c.AllErrorsArray, &err)
+		if c.DryRun {
+			if len(state) > 0 {
+				level.Info(*c.logger).Log("msg", "dry run. skip sending notifications", "service", s.ProcessName)
 			}
+
+			continue
+		}
+
+		// message is nil if notification is suppressed and there is no summary of suppressed notifications
+		if msg := c.rateLimitedMessage(s, state, since, now); msg != nil {
+			c.send(msg, notifiers, now)
 		}
 	}
 
@@ -409,27 +640,36 @@ func (c *Checker) ReportErrors() bool {
 			c.AllErrorsArray = append(c.AllErrorsArray, e)
 		}
 
-		if len(c.Config.to) == 0 {
-			level.Debug(*c.logger).Log("msg", "nanny script doesn't have 'mailing_list'.  skip sending emails")
-
-			return gotErrors
+		notifiers := c.nannyNotifiers()
+
+		switch {
+		case len(notifiers) == 0:
+			level.Debug(*c.logger).Log("msg", "nanny script doesn't have 'mailing_list' or notifiers with 'nanny_errors'. skip sending notifications")
+		case c.DryRun:
+			level.Info(*c.logger).Log("msg", "dry run. skip sending notifications")
+		default:
+			c.send(&notifier.Message{
+				Hostname: c.hostname,
+				Subject:  fmt.Sprintf("%s | Nanny script got errors", c.subjectPrefix),
+				Errors:   c.checkerErrorArray,
+				Time:     time.Now(),
+			}, notifiers, now)
 		}
+	}
 
-		if err := c.Config.Mailer.CheckSettings(); err != nil {
-			level.Warn(*c.logger).Log("msg", "nanny script mail config inconsistent. skip sending emails",
-				"error", err)
-
-			c.AllErrorsArray = append(c.AllErrorsArray, &err)
-
-			return gotErrors
-		}
+	// digests are sent when all notifications of run are collected
+	c.sendDigests(now)
 
-		// switch back to global 'mailing_list'
-		c.Config.Mailer.Headers.To = c.Config.to
-		c.Config.Mailer.Headers.Subject = fmt.Sprintf("%s | Nanny script got errors", subjectPrefix)
+	// state is saved after digests are sent, because delivered notifications are kept in it.
+	// So error of saving isn't notified, it's only logged and changes exit code.
+	if !c.DryRun {
+		if err := c.saveState(); err != nil {
+			level.Error(*c.logger).Log("msg", "got error when try to save state file",
+				"value", c.statePath(), "error", err.Error())
 
-		if err := c.Config.Mailer.SendHtmlEmail(c.checkerErrorArray); err != nil {
-			c.AllErrorsArray = append(c.AllErrorsArray, &err)
+			err1 := fmt.Errorf("'Nanny' script error: %s", err.Error())
+			c.AllErrorsArray = append(c.AllErrorsArray, &err1)
+			gotErrors = true
 		}
 	}
 
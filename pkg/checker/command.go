@@ -0,0 +1,243 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"gopkg.in/yaml.v3"
+
+	npf "github.com/ashokhin/autosys-nanny/pkg/file"
+)
+
+const (
+	SHELL_DEFAULT      string        = "bash"
+	COMMAND_WAIT_DELAY time.Duration = time.Second
+)
+
+var supportedShells = []string{"sh", "bash", "zsh"}
+
+// shellOption is 'shell' property of service.
+// 'false' means direct exec without shell, 'true' or empty value means default shell,
+// otherwise value is name of shell or absolute path to it.
+type shellOption struct {
+	name   string
+	direct bool
+}
+
+func (o *shellOption) UnmarshalYAML(value *yaml.Node) error {
+	var enabled bool
+
+	if value.ShortTag() == "!!bool" {
+		if err := value.Decode(&enabled); err != nil {
+			return err
+		}
+
+		o.direct = !enabled
+
+		return nil
+	}
+
+	if err := value.Decode(&o.name); err != nil {
+		return err
+	}
+
+	if !slices.Contains(supportedShells, filepath.Base(o.name)) ||
+		(strings.Contains(o.name, "/") && !filepath.IsAbs(o.name)) {
+		return fmt.Errorf("line %d: unsupported shell '%s'. supported values: false, true, %s or absolute path to them",
+			value.Line, o.name, strings.Join(supportedShells, ", "))
+	}
+
+	return nil
+}
+
+func (o shellOption) String() string {
+	switch {
+	case o.direct:
+		return "false"
+	case len(o.name) == 0:
+		return SHELL_DEFAULT
+	default:
+		return o.name
+	}
+}
+
+// lookPath searches executable in directories from PATH of service's environment
+// instead of nanny's PATH which is used by exec.LookPath
+func lookPath(file string, env *environ) (string, error) {
+	if strings.Contains(file, "/") {
+		return file, nil
+	}
+
+	pathEnv, _ := env.Lookup("PATH")
+
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if len(dir) == 0 {
+			dir = "."
+		}
+
+		path := filepath.Join(dir, file)
+
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() && fi.Mode()&0111 != 0 {
+			return path, nil
+		}
+	}
+
+	return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+}
+
+// expandArgv builds argv for direct exec: cmdLine is path to executable as is, args are separate arguments.
+// There is no shell to expand '${VAR}' references, so they are expanded with service's environment here.
+func expandArgv(cmdLine string, args []string, env *environ) ([]string, error) {
+	argv := make([]string, 0, len(args)+1)
+
+	for _, arg := range append([]string{cmdLine}, args...) {
+		value, err := npf.ExpandString(arg, env.Lookup)
+
+		if err != nil {
+			return nil, err
+		}
+
+		argv = append(argv, value)
+	}
+
+	return argv, nil
+}
+
+// newCommand builds command with service's environment and credential.
+// With shell cmdLine and args are joined into shell script,
+// with direct exec cmdLine is path to executable (without arguments) and args are passed as separate arguments.
+func (s *Service) newCommand(cmdLine string, args []string) (*exec.Cmd, *environ, error) {
+	var cmd *exec.Cmd
+
+	cred, account, err := s.credential()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	env, err := s.environ(account)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if s.Shell.direct {
+		argv, err := expandArgv(cmdLine, args, env)
+
+		if err != nil {
+			return nil, nil, &ErrBadEnv{s.ProcessName, fmt.Sprintf("can't expand command '%s': %s", cmdLine, err.Error())}
+		}
+
+		if len(argv[0]) == 0 {
+			return nil, nil, &exec.Error{Name: cmdLine, Err: exec.ErrNotFound}
+		}
+
+		path, err := lookPath(argv[0], env)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		cmd = &exec.Cmd{Path: path, Args: argv}
+	} else {
+		if len(args) > 0 {
+			cmdLine = fmt.Sprintf("%s %s", cmdLine, strings.Join(args, " "))
+		}
+
+		shell, err := lookPath(s.Shell.String(), env)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		cmd = &exec.Cmd{Path: shell, Args: []string{s.Shell.String(), "-c", cmdLine}}
+	}
+
+	cmd.Env = env.List()
+	cmd.Dir = s.WorkingDir
+
+	if cred != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: cred}
+
+		level.Debug(*s.Logger).Log("msg", "command credential", "service", s.ProcessName,
+			"uid", cred.Uid, "gid", cred.Gid, "groups", fmt.Sprintf("%v", cred.Groups))
+	}
+
+	return cmd, env, nil
+}
+
+// commandLine returns human readable command line: shell script or joined arguments
+func (s *Service) commandLine(cmd *exec.Cmd) string {
+	if s.Shell.direct {
+		return strings.Join(cmd.Args, " ")
+	}
+
+	return cmd.Args[len(cmd.Args)-1]
+}
+
+// ErrTimeout is error of command killed after timeout
+var ErrTimeout = errors.New("timeout exceeded")
+
+// runCommand runs command in a new process group and returns its combined output.
+// After timeout the whole process group is killed, so command can't leave background children.
+func (s *Service) runCommand(cmd *exec.Cmd, timeout time.Duration) (string, error) {
+	var output bytes.Buffer
+
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	cmd.SysProcAttr.Setpgid = true
+	// background children which keep output pipe open don't block waiting for command
+	cmd.WaitDelay = COMMAND_WAIT_DELAY
+
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	chExit := make(chan error, 1)
+
+	go func() {
+		err := cmd.Wait()
+
+		if errors.Is(err, exec.ErrWaitDelay) {
+			err = nil
+		}
+
+		chExit <- err
+	}()
+
+	// command's timeout is limited by deadline of service's restart
+	ctx, cancel := context.WithTimeout(s.context(), timeout)
+	defer cancel()
+
+	select {
+	case err := <-chExit:
+		return output.String(), err
+	case <-ctx.Done():
+		if s.context().Err() != nil {
+			timeout = s.restartTimeout()
+		}
+
+		level.Warn(*s.Logger).Log("msg", "command timeout exceeded. kill its process group",
+			"service", s.ProcessName, "value", cmd.String(), "timeout", timeout)
+
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-chExit
+
+		return output.String(), fmt.Errorf("%w (%s)", ErrTimeout, timeout)
+	}
+}
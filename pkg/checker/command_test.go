@@ -0,0 +1,71 @@
+package checker
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestNewCommandDirect(t *testing.T) {
+	appHome := filepath.Join(t.TempDir(), "My App")
+	bin := filepath.Join(appHome, "bin", "run")
+
+	if err := os.MkdirAll(filepath.Dir(bin), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestService("cmd-test")
+	s.Shell = shellOption{direct: true}
+	s.EnvList = []string{"APP_HOME=" + appHome}
+
+	cmd, _, err := s.newCommand("${APP_HOME}/bin/run", []string{"--name", "two words", "$${APP_HOME}"})
+
+	if err != nil {
+		t.Fatalf("newCommand() unexpected error: %v", err)
+	}
+
+	want := []string{bin, "--name", "two words", "${APP_HOME}"}
+
+	if cmd.Path != bin || !slices.Equal(cmd.Args, want) {
+		t.Errorf("newCommand() = %q %q, want %q %q", cmd.Path, cmd.Args, bin, want)
+	}
+
+	// command isn't split, so inline arguments are a part of executable path
+	if cmd, _, err = s.newCommand("${APP_HOME}/bin/run --name", nil); err != nil {
+		t.Fatalf("newCommand() unexpected error: %v", err)
+	}
+
+	if len(cmd.Args) != 1 {
+		t.Errorf("newCommand() with inline arguments = %q, want single argument", cmd.Args)
+	}
+
+	if _, _, err := s.newCommand("${NANNY_TEST_UNDEFINED}/run", nil); err == nil {
+		t.Error("newCommand() with undefined variable returns no error")
+	}
+}
+
+func TestNewCommandShell(t *testing.T) {
+	s := newTestService("cmd-test")
+	s.Shell = shellOption{name: "sh"}
+
+	cmd, _, err := s.newCommand("echo ${APP_HOME:-/opt}", []string{"--name", "value"})
+
+	if err != nil {
+		t.Fatalf("newCommand() unexpected error: %v", err)
+	}
+
+	want := []string{"sh", "-c", "echo ${APP_HOME:-/opt} --name value"}
+
+	if !slices.Equal(cmd.Args, want) {
+		t.Errorf("newCommand() args = %q, want %q", cmd.Args, want)
+	}
+
+	if s.commandLine(cmd) != want[2] {
+		t.Errorf("commandLine() = %q, want %q", s.commandLine(cmd), want[2])
+	}
+}
@@ -4,14 +4,39 @@ import (
 	"fmt"
 
 	"github.com/ashokhin/autosys-nanny/pkg/mailer"
+	"github.com/ashokhin/autosys-nanny/pkg/notifier"
 )
 
 type CheckerConfig struct {
-	Services []*Service     `yaml:"services_list"`
-	Mailer   *mailer.Mailer `yaml:"general"`
-	to       []string
+	Services  []*Service         `yaml:"services_list"`
+	Mailer    *mailer.Mailer     `yaml:"general"`
+	Notifiers []*notifier.Config `yaml:"notifiers"`
+	Alerting  *AlertingConfig    `yaml:"alerting"`
 }
 
 func (c *CheckerConfig) String() string {
 	return fmt.Sprintf("%+v", *c)
 }
+
+// checkNotifiers returns error if notifiers have duplicate names or service refers to unknown notifier
+func (c *CheckerConfig) checkNotifiers() error {
+	names := make(map[string]bool)
+
+	for _, n := range c.Notifiers {
+		if names[n.Name] {
+			return fmt.Errorf("notifiers: duplicate notifier name '%s'", n.Name)
+		}
+
+		names[n.Name] = true
+	}
+
+	for _, s := range c.Services {
+		for _, name := range s.Notifiers {
+			if !names[name] {
+				return fmt.Errorf("services_list: service '%s' refers to unknown notifier '%s'", s.ProcessName, name)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package checker
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// lookup user by name or numeric id
+func lookupUser(name string) (*user.User, error) {
+	if _, err := strconv.Atoi(name); err == nil {
+		return user.LookupId(name)
+	}
+
+	return user.Lookup(name)
+}
+
+// lookup group by name or numeric id
+func lookupGroup(name string) (*user.Group, error) {
+	if _, err := strconv.Atoi(name); err == nil {
+		return user.LookupGroupId(name)
+	}
+
+	return user.LookupGroup(name)
+}
+
+func parseId(id string) (uint32, error) {
+	value, err := strconv.ParseUint(id, 10, 32)
+
+	return uint32(value), err
+}
+
+// credential returns credential for service's commands defined by 'user', 'group' and 'supplementary_groups'
+// and account of 'user' (nil if 'user' isn't defined).
+// nil credential means that commands are executed with nanny's credential.
+func (s *Service) credential() (*syscall.Credential, *user.User, error) {
+	var err error
+	var account *user.User
+
+	if len(s.User) == 0 && len(s.Group) == 0 && len(s.SuppGroups) == 0 {
+		return nil, nil, nil
+	}
+
+	cred := &syscall.Credential{
+		Uid: uint32(os.Getuid()),
+		Gid: uint32(os.Getgid()),
+	}
+
+	if len(s.User) > 0 {
+		if account, err = lookupUser(s.User); err != nil {
+			return nil, nil, &ErrBadCredential{s.ProcessName, err.Error()}
+		}
+
+		if cred.Uid, err = parseId(account.Uid); err != nil {
+			return nil, nil, &ErrBadCredential{s.ProcessName, fmt.Sprintf("user '%s' has non-numeric uid '%s'", s.User, account.Uid)}
+		}
+
+		if cred.Gid, err = parseId(account.Gid); err != nil {
+			return nil, nil, &ErrBadCredential{s.ProcessName, fmt.Sprintf("user '%s' has non-numeric gid '%s'", s.User, account.Gid)}
+		}
+	}
+
+	if len(s.Group) > 0 {
+		group, err := lookupGroup(s.Group)
+
+		if err != nil {
+			return nil, nil, &ErrBadCredential{s.ProcessName, err.Error()}
+		}
+
+		if cred.Gid, err = parseId(group.Gid); err != nil {
+			return nil, nil, &ErrBadCredential{s.ProcessName, fmt.Sprintf("group '%s' has non-numeric gid '%s'", s.Group, group.Gid)}
+		}
+	}
+
+	groupNames := s.SuppGroups
+
+	// without explicit 'supplementary_groups' service gets all groups of 'user' like after login
+	if len(groupNames) == 0 && account != nil {
+		if groupNames, err = account.GroupIds(); err != nil {
+			return nil, nil, &ErrBadCredential{s.ProcessName, fmt.Sprintf("can't get groups of user '%s': %s", s.User, err.Error())}
+		}
+	}
+
+	for _, name := range groupNames {
+		group, err := lookupGroup(name)
+
+		if err != nil {
+			return nil, nil, &ErrBadCredential{s.ProcessName, err.Error()}
+		}
+
+		gid, err := parseId(group.Gid)
+
+		if err != nil {
+			return nil, nil, &ErrBadCredential{s.ProcessName, fmt.Sprintf("group '%s' has non-numeric gid '%s'", name, group.Gid)}
+		}
+
+		cred.Groups = append(cred.Groups, gid)
+	}
+
+	// unprivileged nanny can't call setgroups(2) even for own groups, so don't switch credential at all
+	if os.Getuid() != 0 && cred.Uid == uint32(os.Getuid()) && cred.Gid == uint32(os.Getgid()) &&
+		len(s.SuppGroups) == 0 {
+		return nil, account, nil
+	}
+
+	return cred, account, nil
+}
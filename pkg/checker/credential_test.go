@@ -0,0 +1,59 @@
+package checker
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestServiceCredential(t *testing.T) {
+	s := newTestService("cred-test")
+
+	if cred, account, err := s.credential(); cred != nil || account != nil || err != nil {
+		t.Fatalf("credential() without user = %v, %v, %v, want nil credential", cred, account, err)
+	}
+
+	s.User = "root"
+	s.SuppGroups = []string{"0"}
+
+	cred, account, err := s.credential()
+
+	if err != nil {
+		t.Fatalf("credential() unexpected error: %v", err)
+	}
+
+	if cred.Uid != 0 || cred.Gid != 0 || !slices.Equal(cred.Groups, []uint32{0}) {
+		t.Errorf("credential() = %+v, want uid 0, gid 0, groups [0]", *cred)
+	}
+
+	env, err := s.environ(account)
+
+	if err != nil {
+		t.Fatalf("environ() unexpected error: %v", err)
+	}
+
+	if value, _ := env.Lookup("HOME"); value != account.HomeDir {
+		t.Errorf("HOME = %q, want home of 'user' %q", value, account.HomeDir)
+	}
+
+	if value, _ := env.Lookup("USER"); value != "root" {
+		t.Errorf("USER = %q, want %q", value, "root")
+	}
+}
+
+func TestServiceCredentialUnknown(t *testing.T) {
+	s := newTestService("cred-test")
+	s.User = "nanny-test-missing-user"
+
+	_, _, err := s.credential()
+
+	if _, ok := err.(*ErrBadCredential); !ok {
+		t.Fatalf("credential() error = %v, want ErrBadCredential", err)
+	}
+
+	s.User = ""
+	s.Group = "nanny-test-missing-group"
+
+	if _, _, err := s.credential(); err == nil {
+		t.Fatal("credential() with unknown group returns no error")
+	}
+}
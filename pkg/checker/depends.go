@@ -0,0 +1,73 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// indexes of services by 'process_name'
+func (c *CheckerConfig) servicesByName() map[string][]int {
+	byName := make(map[string][]int)
+
+	for i, s := range c.Services {
+		if len(s.ProcessName) > 0 {
+			byName[s.ProcessName] = append(byName[s.ProcessName], i)
+		}
+	}
+
+	return byName
+}
+
+// checkDependencies returns error if 'depends_on' refers to unknown service or dependencies have a cycle
+func (c *CheckerConfig) checkDependencies() error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	byName := c.servicesByName()
+	state := make([]int, len(c.Services))
+
+	var visit func(i int, path []string) error
+
+	visit = func(i int, path []string) error {
+		s := c.Services[i]
+		path = append(path, s.ProcessName)
+
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("services_list: dependency cycle '%s'", strings.Join(path, "' -> '"))
+		case visited:
+			return nil
+		}
+
+		state[i] = visiting
+
+		for _, name := range s.DependsOn {
+			deps, ok := byName[name]
+
+			if !ok {
+				return fmt.Errorf("services_list: service '%s' depends on unknown service '%s'", s.ProcessName, name)
+			}
+
+			for _, dep := range deps {
+				if err := visit(dep, path); err != nil {
+					return err
+				}
+			}
+		}
+
+		state[i] = visited
+
+		return nil
+	}
+
+	for i := range c.Services {
+		if err := visit(i, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestCheckDependencies(t *testing.T) {
+	newConfig := func(dependsOn map[string][]string) *CheckerConfig {
+		c := &CheckerConfig{}
+
+		for _, name := range []string{"db", "app", "web"} {
+			s := newTestService(name)
+			s.DependsOn = dependsOn[name]
+			c.Services = append(c.Services, s)
+		}
+
+		return c
+	}
+
+	if err := newConfig(map[string][]string{"app": {"db"}, "web": {"app", "db"}}).checkDependencies(); err != nil {
+		t.Errorf("checkDependencies() unexpected error: %v", err)
+	}
+
+	err := newConfig(map[string][]string{"app": {"cache"}}).checkDependencies()
+
+	if err == nil || !strings.Contains(err.Error(), "unknown service 'cache'") {
+		t.Errorf("checkDependencies() with unknown service error = %v", err)
+	}
+
+	err = newConfig(map[string][]string{"db": {"web"}, "app": {"db"}, "web": {"app"}}).checkDependencies()
+
+	if err == nil || !strings.Contains(err.Error(), "dependency cycle 'db' -> 'web' -> 'app' -> 'db'") {
+		t.Errorf("checkDependencies() with cycle error = %v", err)
+	}
+}
+
+func TestCheckAndRestartDependsOn(t *testing.T) {
+	dir := t.TempDir()
+	orderFile := filepath.Join(dir, "order")
+	config := filepath.Join(dir, "services.yaml")
+	prefix := fmt.Sprintf("nanny-depends-test-%d", os.Getpid())
+
+	// 'first' is listed after 'second' and is slower to start, so 'second' is started first
+	// only if it doesn't wait for its dependency
+	data := fmt.Sprintf(`services_list:
+  - process_name: "%[1]s-second"
+    start_cmd: "echo second >> %[2]s; exec sleep 1"
+    shell: "sh"
+    start_wait: "100ms"
+    depends_on: ["%[1]s-first"]
+  - process_name: "%[1]s-first"
+    start_cmd: "sleep 0.2; echo first >> %[2]s; exec sleep 1"
+    shell: "sh"
+    start_wait: "300ms"
+`, prefix, orderFile)
+
+	if err := os.WriteFile(config, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := log.NewNopLogger()
+	c := &Checker{PropertiesFilePath: config, ConcurrentWorkers: 4, RestartWorkers: 2}
+	c.NewLogger(&logger)
+
+	if err := c.CheckAndRestart(); err != nil {
+		t.Fatalf("CheckAndRestart() unexpected error: %v", err)
+	}
+
+	order, err := os.ReadFile(orderFile)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(order) != "first\nsecond\n" {
+		t.Errorf("services started in order %q, want dependency first", order)
+	}
+}
+
+func TestRestartTimeout(t *testing.T) {
+	s := newTestService("timeout-test")
+	s.Shell = shellOption{name: "sh"}
+	s.RestartLimit = 200 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.restartTimeout())
+	defer cancel()
+
+	s.ctx = ctx
+
+	cmd, _, err := s.newCommand("sleep 5", nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timeStart := time.Now()
+
+	if _, err := s.runCommand(cmd, time.Minute); err == nil {
+		t.Error("runCommand() after 'restart_timeout' returns no error")
+	}
+
+	if elapsed := time.Since(timeStart); elapsed > 2*time.Second {
+		t.Errorf("runCommand() returned after %s, want it killed after 'restart_timeout'", elapsed)
+	}
+
+	// 'restart_timeout' has already expired, so start can't be confirmed
+	s.StartWait = time.Minute
+
+	if err := s.waitStarted(0, make(chan error)); err == nil {
+		t.Error("waitStarted() after 'restart_timeout' returns no error")
+	} else if _, ok := err.(*ErrStartTimeout); !ok {
+		t.Errorf("waitStarted() error = %v, want ErrStartTimeout", err)
+	}
+}
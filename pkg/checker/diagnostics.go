@@ -0,0 +1,115 @@
+package checker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ashokhin/autosys-nanny/pkg/mailer"
+)
+
+const (
+	DIAGNOSTICS_DEFAULT_TIMEOUT time.Duration = 30 * time.Second
+	// maximum number of lines of inline output
+	DIAGNOSTICS_INLINE_LINES int = 100
+)
+
+// Diagnostics is command executed before service's restart.
+// Its output is added to notification inline or as attachment of email.
+type Diagnostics struct {
+	Cmd     string        `yaml:"cmd"`
+	Timeout time.Duration `yaml:"timeout"`
+	EnvList []string      `yaml:"env_vars"`
+	Attach  bool          `yaml:"attach"`
+}
+
+func (d *Diagnostics) UnmarshalYAML(value *yaml.Node) error {
+	type rawDiagnostics Diagnostics
+
+	if value.Kind == yaml.ScalarNode {
+		d.Cmd = value.Value
+	} else if err := value.Decode((*rawDiagnostics)(d)); err != nil {
+		return err
+	}
+
+	if len(d.Cmd) == 0 {
+		return fmt.Errorf("line %d: diagnostics should contain command in 'cmd' property", value.Line)
+	}
+
+	return nil
+}
+
+func (d *Diagnostics) timeout() time.Duration {
+	if d.Timeout > 0 {
+		return d.Timeout
+	}
+
+	return DIAGNOSTICS_DEFAULT_TIMEOUT
+}
+
+// runDiagnostics executes service's diagnostic command and keeps its output for notification.
+// Failed command doesn't prevent restart, its error is added to output.
+func (s *Service) runDiagnostics() {
+	// disabled service is stopped without notification about failure
+	if s.Diagnostics == nil || s.Disabled {
+		return
+	}
+
+	output, err := s.execDiagnostics()
+
+	if err != nil {
+		level.Warn(*s.Logger).Log("msg", "diagnostic command failed", "service", s.ProcessName,
+			"error", err.Error())
+
+		output = fmt.Sprintf("%s\ndiagnostic command failed: %s", strings.TrimRight(output, "\n"), err.Error())
+	}
+
+	s.diagOutput = output
+}
+
+func (s *Service) execDiagnostics() (string, error) {
+	cmd, env, err := s.newCommand(s.Diagnostics.Cmd, nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := env.loadList(s.Diagnostics.EnvList); err != nil {
+		return "", fmt.Errorf("can't interpolate 'env_vars': %w", err)
+	}
+
+	env.Set("NANNY_SERVICE", s.ProcessName)
+	env.Set("NANNY_REASON", s.hookReason())
+
+	if s.process != nil {
+		env.Set("NANNY_PID", strconv.Itoa(s.process.Pid))
+	}
+
+	cmd.Env = env.List()
+
+	level.Debug(*s.Logger).Log("msg", "execute diagnostic command", "service", s.ProcessName,
+		"value", cmd.String(), "timeout", s.Diagnostics.timeout())
+
+	return s.runCommand(cmd, s.Diagnostics.timeout())
+}
+
+// diagnostics returns output of diagnostic command as lines of notification or as attachment
+func (s *Service) diagnostics() ([]string, []*mailer.Attachment) {
+	if len(s.diagOutput) == 0 {
+		return nil, nil
+	}
+
+	if !s.Diagnostics.Attach {
+		return tailLines(s.diagOutput, DIAGNOSTICS_INLINE_LINES), nil
+	}
+
+	return nil, []*mailer.Attachment{{
+		Name:        fmt.Sprintf("%s-diagnostics.txt", safeFileName(s.ProcessName)),
+		ContentType: "text/plain; charset=utf-8",
+		Content:     []byte(s.diagOutput),
+	}}
+}
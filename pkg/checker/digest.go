@@ -0,0 +1,97 @@
+package checker
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ashokhin/autosys-nanny/pkg/notifier"
+)
+
+// digestGroup is notifications of run to the same recipients
+type digestGroup struct {
+	notifier notifier.Notifier
+	messages []*notifier.Message
+}
+
+// send sends message by notifiers right away or, with 'digest', adds it into digests of notifiers' recipients
+func (c *Checker) send(msg *notifier.Message, notifiers []notifier.Notifier, now time.Time) {
+	c.renderSubject(msg)
+
+	if !c.Config.alerting().Digest {
+		if c.notify(msg, notifiers) {
+			c.notified(msg.Service, now)
+		}
+
+		return
+	}
+
+	for _, n := range notifiers {
+		key := notifier.RecipientKey(n)
+		group, ok := c.digests[key]
+
+		if !ok {
+			group = &digestGroup{notifier: n}
+			c.digests[key] = group
+			c.digestKeys = append(c.digestKeys, key)
+		}
+
+		// service may refer to several notifiers with the same recipients
+		if !slices.Contains(group.messages, msg) {
+			group.messages = append(group.messages, msg)
+		}
+	}
+}
+
+// sendDigests sends one message to every recipients. Single notification is sent as is.
+func (c *Checker) sendDigests(now time.Time) {
+	for _, key := range c.digestKeys {
+		group := c.digests[key]
+		msg := group.messages[0]
+
+		if len(group.messages) > 1 {
+			msg = c.digestMessage(group.messages, now)
+		}
+
+		if !c.notify(msg, []notifier.Notifier{group.notifier}) {
+			continue
+		}
+
+		for _, m := range group.messages {
+			c.notified(m.Service, now)
+		}
+	}
+}
+
+// digestMessage returns message with summary of messages and counts of services and errors in subject
+func (c *Checker) digestMessage(messages []*notifier.Message, now time.Time) *notifier.Message {
+	var summary []*error
+
+	servicesCount := 0
+	errorsCount := 0
+
+	for _, m := range messages {
+		line := fmt.Errorf("Nanny script: %d errors", len(m.Errors))
+
+		if len(m.Service) > 0 {
+			servicesCount++
+			line = fmt.Errorf("service '%s': %s, %d errors", m.Service, m.State, len(m.Errors))
+		}
+
+		errorsCount += len(m.Errors)
+		summary = append(summary, &line)
+	}
+
+	msg := &notifier.Message{
+		Hostname: c.hostname,
+		State:    STATE_DIGEST,
+		Subject:  fmt.Sprintf("%s | Nanny digest - %d services, %d errors", c.subjectPrefix, servicesCount, errorsCount),
+		Errors:   summary,
+		Time:     now,
+		Digest:   messages,
+	}
+
+	c.renderSubject(msg)
+
+	return msg
+}
@@ -0,0 +1,101 @@
+package checker
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashokhin/autosys-nanny/pkg/notifier"
+)
+
+func testServiceMessage(service string, state string, errs ...string) *notifier.Message {
+	msg := &notifier.Message{Service: service, State: state, Subject: "alert of " + service}
+
+	for _, e := range errs {
+		err := errors.New(e)
+		msg.Errors = append(msg.Errors, &err)
+	}
+
+	return msg
+}
+
+func TestDigestGrouping(t *testing.T) {
+	c := newTestChecker()
+	c.Config.Alerting.Digest = true
+	c.digests = make(map[string]*digestGroup)
+	c.templates, _ = notifier.NewTemplates("", "", "")
+	now := time.Now()
+
+	// the same recipients in different order and notifiers with other recipients
+	ops := notifier.NewSmtp("mailing_list", nil, nil, []string{"ops@example.com", "dev@example.com"})
+	opsCopy := notifier.NewSmtp("ops", nil, nil, []string{"dev@example.com", "ops@example.com"})
+	dev := notifier.NewSmtp("mailing_list", nil, nil, []string{"dev@example.com"})
+	chat := &testNotifier{name: "chat"}
+
+	c.send(testServiceMessage("app", STATE_RESTARTED, "restarted"), []notifier.Notifier{ops, chat}, now)
+	c.send(testServiceMessage("db", STATE_RESTART_FAILED, "start failed", "hook failed"), []notifier.Notifier{opsCopy, dev}, now)
+	c.send(testServiceMessage("web", STATE_RESTARTED, "restarted"), []notifier.Notifier{ops, opsCopy}, now)
+
+	if len(chat.messages) > 0 {
+		t.Fatal("notification is sent before digests")
+	}
+
+	if len(c.digestKeys) != 3 {
+		t.Fatalf("digests = %q, want ops and dev emails and chat", c.digestKeys)
+	}
+
+	group := c.digests[notifier.RecipientKey(ops)]
+
+	if group != c.digests[notifier.RecipientKey(opsCopy)] || len(group.messages) != 3 {
+		t.Fatalf("digest of the same recipients has %d messages, want 3 without duplicates", len(group.messages))
+	}
+
+	msg := c.digestMessage(group.messages, now)
+
+	if !strings.Contains(msg.Subject, "Nanny digest - 3 services, 4 errors") || msg.State != STATE_DIGEST {
+		t.Errorf("digest subject = %q, want counts of services and errors", msg.Subject)
+	}
+
+	if msg.ErrorsCount() != 4 || len(msg.Digest) != 3 {
+		t.Errorf("digest has %d errors in %d messages, want 4 in 3", msg.ErrorsCount(), len(msg.Digest))
+	}
+}
+
+func TestSendDigests(t *testing.T) {
+	c := newTestChecker()
+	c.Config.Alerting.Digest = true
+	c.digests = make(map[string]*digestGroup)
+	c.templates, _ = notifier.NewTemplates("", "", "")
+	now := time.Now()
+
+	c.state.Services["app"] = &ServiceState{State: STATE_RESTART_FAILED, Since: now}
+	c.state.Services["db"] = &ServiceState{State: STATE_DOWN, Since: now}
+
+	chat := &testNotifier{name: "chat"}
+	pager := &testNotifier{name: "pager", err: errors.New("connection refused")}
+
+	c.send(testServiceMessage("app", STATE_RESTART_FAILED, "start failed"), []notifier.Notifier{chat}, now)
+	c.send(testServiceMessage("web", STATE_RESTARTED, "restarted"), []notifier.Notifier{chat}, now)
+	c.send(testServiceMessage("db", STATE_DOWN, "no start_cmd"), []notifier.Notifier{pager}, now)
+
+	c.sendDigests(now)
+
+	if len(chat.messages) != 1 || len(chat.messages[0].Digest) != 2 {
+		t.Fatalf("chat got %d messages, want one digest of 2 services", len(chat.messages))
+	}
+
+	// single notification is sent as is
+	if len(pager.messages) != 1 || len(pager.messages[0].Digest) != 0 || pager.messages[0].Service != "db" {
+		t.Errorf("pager got %v, want notification of 'db' without digest", pager.messages)
+	}
+
+	// only delivered notifications delay reminders
+	if !c.state.Services["app"].LastNotified.Equal(now) {
+		t.Error("delivered notification of 'app' isn't kept in state")
+	}
+
+	if !c.state.Services["db"].LastNotified.IsZero() {
+		t.Error("undelivered notification of 'db' is kept in state")
+	}
+}
@@ -0,0 +1,148 @@
+package checker
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	npf "github.com/ashokhin/autosys-nanny/pkg/file"
+)
+
+const (
+	ENV_DEFAULT_PATH string = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+	ENV_MASKED_VALUE string = "******"
+)
+
+// variables copied from nanny environment when service has 'clear_env: true'
+var minimalEnvKeys = []string{"HOME", "USER", "LOGNAME", "LANG", "TZ"}
+
+// names of variables which values are masked in debug and dry run output
+var secretEnvKeyRegexp = regexp.MustCompile(`(?i)(PASSWORD|PASSWD|SECRET|TOKEN|CREDENTIAL|API_?KEY|PRIVATE_?KEY|AUTH)`)
+
+// environ is ordered set of environment variables
+type environ struct {
+	keys    []string
+	values  map[string]string
+	secrets map[string]bool
+}
+
+func newEnviron(list []string) *environ {
+	e := &environ{
+		values:  make(map[string]string),
+		secrets: make(map[string]bool),
+	}
+
+	for _, kv := range list {
+		key, value, _ := strings.Cut(kv, "=")
+		e.Set(key, value)
+	}
+
+	return e
+}
+
+// minimalEnviron returns environment with default PATH and basic variables of nanny process
+func minimalEnviron() *environ {
+	e := newEnviron([]string{"PATH=" + ENV_DEFAULT_PATH})
+
+	for _, key := range minimalEnvKeys {
+		if value, ok := os.LookupEnv(key); ok {
+			e.Set(key, value)
+		}
+	}
+
+	return e
+}
+
+func (e *environ) Set(key, value string) {
+	if _, ok := e.values[key]; !ok {
+		e.keys = append(e.keys, key)
+	}
+
+	e.values[key] = value
+}
+
+func (e *environ) Lookup(key string) (string, bool) {
+	value, ok := e.values[key]
+
+	return value, ok
+}
+
+// MarkSecret forces masking of variable value in output
+func (e *environ) MarkSecret(key string) {
+	e.secrets[key] = true
+}
+
+func (e *environ) isSecret(key string) bool {
+	return e.secrets[key] || secretEnvKeyRegexp.MatchString(key)
+}
+
+// List returns variables in 'KEY=value' format suitable for exec.Cmd.Env
+func (e *environ) List() []string {
+	list := make([]string, 0, len(e.keys))
+
+	for _, key := range e.keys {
+		list = append(list, key+"="+e.values[key])
+	}
+
+	return list
+}
+
+// MaskedList returns variables in 'KEY=value' format with secret values masked
+func (e *environ) MaskedList() []string {
+	list := make([]string, 0, len(e.keys))
+
+	for _, key := range e.keys {
+		if e.isSecret(key) {
+			list = append(list, key+"="+ENV_MASKED_VALUE)
+		} else {
+			list = append(list, key+"="+e.values[key])
+		}
+	}
+
+	return list
+}
+
+// add variables from dotenv file, values are interpolated with already defined variables
+func (e *environ) loadFile(filePath string) error {
+	entries, err := npf.ReadEnvFile(filePath)
+
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		value := entry.Value
+
+		if entry.Expand {
+			if value, err = npf.ExpandString(value, e.Lookup); err != nil {
+				return err
+			}
+		}
+
+		e.Set(entry.Key, value)
+	}
+
+	return nil
+}
+
+// add variables from 'KEY=value' list, values are interpolated with already defined variables
+func (e *environ) loadList(list []string) error {
+	for _, kv := range list {
+		key, rawValue, _ := strings.Cut(kv, "=")
+
+		value, err := npf.ExpandString(rawValue, e.Lookup)
+
+		if err != nil {
+			return err
+		}
+
+		e.Set(key, value)
+
+		// value read from secret file
+		if strings.Contains(rawValue, "${file:") {
+			e.MarkSecret(key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package checker
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/go-kit/log"
+)
+
+func newTestService(name string) *Service {
+	logger := log.NewNopLogger()
+
+	return &Service{ProcessName: name, Logger: &logger}
+}
+
+func TestServiceEnviron(t *testing.T) {
+	t.Setenv("NANNY_TEST_INHERITED", "inherited")
+	t.Setenv("HOME", "/home/nanny")
+
+	envFile := filepath.Join(t.TempDir(), "app.env")
+	secretFile := filepath.Join(t.TempDir(), "secret")
+
+	if err := os.WriteFile(envFile, []byte("APP_HOME=/opt/app\nAPP_BIN=${APP_HOME}/bin\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(secretFile, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestService("env-test")
+	s.EnvFiles = stringList{envFile}
+	s.EnvList = []string{"PATH=${APP_BIN}:${PATH}", "DB_PASS=${file:" + secretFile + "}", "PLAIN=value"}
+	s.SecretVars = []string{"PLAIN"}
+
+	env, err := s.environ(nil)
+
+	if err != nil {
+		t.Fatalf("environ() unexpected error: %v", err)
+	}
+
+	if value, _ := env.Lookup("NANNY_TEST_INHERITED"); value != "inherited" {
+		t.Errorf("inherited variable = %q, want %q", value, "inherited")
+	}
+
+	if value, _ := env.Lookup("PATH"); value != "/opt/app/bin:"+os.Getenv("PATH") {
+		t.Errorf("PATH = %q, want it extended with APP_BIN from env_file", value)
+	}
+
+	if value, _ := env.Lookup("DB_PASS"); value != "s3cret" {
+		t.Errorf("DB_PASS = %q, want %q", value, "s3cret")
+	}
+
+	masked := env.MaskedList()
+
+	for _, kv := range []string{"DB_PASS=" + ENV_MASKED_VALUE, "PLAIN=" + ENV_MASKED_VALUE, "APP_HOME=/opt/app"} {
+		if !slices.Contains(masked, kv) {
+			t.Errorf("MaskedList() doesn't contain %q", kv)
+		}
+	}
+
+	// clear_env keeps only minimal environment
+	s.ClearEnv = true
+	s.EnvFiles = nil
+	s.EnvList = []string{"APP_PATH=${PATH}"}
+
+	if env, err = s.environ(nil); err != nil {
+		t.Fatalf("environ() with clear_env unexpected error: %v", err)
+	}
+
+	if _, ok := env.Lookup("NANNY_TEST_INHERITED"); ok {
+		t.Error("environ() with clear_env inherits nanny's variables")
+	}
+
+	if value, _ := env.Lookup("HOME"); value != "/home/nanny" {
+		t.Errorf("HOME = %q, want %q", value, "/home/nanny")
+	}
+
+	if value, _ := env.Lookup("APP_PATH"); value != ENV_DEFAULT_PATH {
+		t.Errorf("APP_PATH = %q, want %q", value, ENV_DEFAULT_PATH)
+	}
+}
+
+func TestServiceEnvironUndefined(t *testing.T) {
+	s := newTestService("env-test")
+	s.ClearEnv = true
+	s.EnvList = []string{"APP=${NANNY_TEST_UNDEFINED}"}
+
+	_, err := s.environ(nil)
+
+	if _, ok := err.(*ErrBadEnv); !ok {
+		t.Fatalf("environ() error = %v, want ErrBadEnv", err)
+	}
+}
@@ -2,6 +2,8 @@ package checker
 
 import (
 	"fmt"
+	"strings"
+	"time"
 )
 
 type ErrNoProcName struct{}
@@ -61,3 +63,168 @@ func (e *ErrSvcRestartedForce) String() string {
 func (e *ErrSvcRestartedForce) Error() string {
 	return fmt.Sprintf("service '%s' restarted with key --force-restart", e.service)
 }
+
+type ErrBadEnv struct {
+	service string
+	message string
+}
+
+func (e *ErrBadEnv) String() string {
+	return fmt.Sprintf("%+v", *e)
+}
+
+func (e *ErrBadEnv) Error() string {
+	return fmt.Sprintf("service '%s' environment error: %s", e.service, e.message)
+}
+
+type ErrBadCredential struct {
+	service string
+	message string
+}
+
+func (e *ErrBadCredential) String() string {
+	return fmt.Sprintf("%+v", *e)
+}
+
+func (e *ErrBadCredential) Error() string {
+	return fmt.Sprintf("service '%s' can't switch user: %s", e.service, e.message)
+}
+
+type ErrStartFailed struct {
+	service    string
+	wait       time.Duration
+	exitStatus string
+	stderrTail []string
+}
+
+func (e *ErrStartFailed) String() string {
+	return fmt.Sprintf("%+v", *e)
+}
+
+func (e *ErrStartFailed) Error() string {
+	message := fmt.Sprintf("service '%s' start failed. service exited within %s after start with %s", e.service, e.wait, e.exitStatus)
+
+	if len(e.stderrTail) > 0 {
+		message = fmt.Sprintf("%s. last lines of stderr:\n%s", message, strings.Join(e.stderrTail, "\n"))
+	}
+
+	return message
+}
+
+type ErrStartTimeout struct {
+	service string
+	timeout time.Duration
+}
+
+func (e *ErrStartTimeout) String() string {
+	return fmt.Sprintf("%+v", *e)
+}
+
+func (e *ErrStartTimeout) Error() string {
+	return fmt.Sprintf("service '%s' start wasn't confirmed. 'restart_timeout' %s exceeded during 'start_wait'",
+		e.service, e.timeout)
+}
+
+type ErrLimits struct {
+	service string
+	message string
+}
+
+func (e *ErrLimits) String() string {
+	return fmt.Sprintf("%+v", *e)
+}
+
+func (e *ErrLimits) Error() string {
+	return fmt.Sprintf("service '%s' started without requested limits: %s", e.service, e.message)
+}
+
+type ErrCgroup struct {
+	service string
+	message string
+}
+
+func (e *ErrCgroup) String() string {
+	return fmt.Sprintf("%+v", *e)
+}
+
+func (e *ErrCgroup) Error() string {
+	return fmt.Sprintf("service '%s' cgroup error: %s", e.service, e.message)
+}
+
+type ErrExcessInstances struct {
+	service string
+	desired int
+	pids    []int
+}
+
+func (e *ErrExcessInstances) String() string {
+	return fmt.Sprintf("%+v", *e)
+}
+
+func (e *ErrExcessInstances) Error() string {
+	return fmt.Sprintf("service '%s' has %d instances running while 'instances' is %d. pids: %v",
+		e.service, len(e.pids), e.desired, e.pids)
+}
+
+type ErrDuplicateProcess struct {
+	service   string
+	processes []*Process
+}
+
+func (e *ErrDuplicateProcess) String() string {
+	return fmt.Sprintf("%+v", *e)
+}
+
+func (e *ErrDuplicateProcess) Error() string {
+	var details []string
+
+	for _, p := range e.processes {
+		details = append(details, fmt.Sprintf("pid %d (ppid %d, started %s): '%s'",
+			p.Pid, p.PPid, p.ModTime.Format(time.RFC3339), p.Cmdline))
+	}
+
+	return fmt.Sprintf("service '%s' is running %d times. duplicate processes:\n%s",
+		e.service, len(e.processes), strings.Join(details, "\n"))
+}
+
+type ErrHook struct {
+	service    string
+	hook       string
+	message    string
+	outputTail []string
+}
+
+func (e *ErrHook) String() string {
+	return fmt.Sprintf("%+v", *e)
+}
+
+func (e *ErrHook) Error() string {
+	message := fmt.Sprintf("service '%s' hook '%s' failed: %s", e.service, e.hook, e.message)
+
+	if len(e.outputTail) > 0 {
+		message = fmt.Sprintf("%s. last lines of output:\n%s", message, strings.Join(e.outputTail, "\n"))
+	}
+
+	return message
+}
+
+type ErrStopCmd struct {
+	service    string
+	command    string
+	message    string
+	outputTail []string
+}
+
+func (e *ErrStopCmd) String() string {
+	return fmt.Sprintf("%+v", *e)
+}
+
+func (e *ErrStopCmd) Error() string {
+	message := fmt.Sprintf("service '%s' stop command '%s' failed: %s", e.service, e.command, e.message)
+
+	if len(e.outputTail) > 0 {
+		message = fmt.Sprintf("%s. last lines of output:\n%s", message, strings.Join(e.outputTail, "\n"))
+	}
+
+	return message
+}
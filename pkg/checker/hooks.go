@@ -0,0 +1,183 @@
+package checker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	HOOK_DEFAULT_TIMEOUT time.Duration = 30 * time.Second
+	HOOK_PRE_START       string        = "pre_start"
+	HOOK_POST_START      string        = "post_start"
+	HOOK_PRE_STOP        string        = "pre_stop"
+	HOOK_POST_STOP       string        = "post_stop"
+	HOOK_ON_FAILURE      string        = "on_failure"
+)
+
+// Hook is command executed around service's restart.
+// It's defined by command string or by object with 'cmd', 'timeout' and 'env_vars'.
+type Hook struct {
+	Cmd     string        `yaml:"cmd"`
+	Timeout time.Duration `yaml:"timeout"`
+	EnvList []string      `yaml:"env_vars"`
+}
+
+func (h *Hook) UnmarshalYAML(value *yaml.Node) error {
+	type rawHook Hook
+
+	if value.Kind == yaml.ScalarNode {
+		h.Cmd = value.Value
+	} else if err := value.Decode((*rawHook)(h)); err != nil {
+		return err
+	}
+
+	if len(h.Cmd) == 0 {
+		return fmt.Errorf("line %d: hook should contain command in 'cmd' property", value.Line)
+	}
+
+	return nil
+}
+
+func (h *Hook) timeout() time.Duration {
+	if h.Timeout > 0 {
+		return h.Timeout
+	}
+
+	return HOOK_DEFAULT_TIMEOUT
+}
+
+// Hooks is 'hooks' property of service
+type Hooks struct {
+	PreStart  *Hook `yaml:"pre_start"`
+	PostStart *Hook `yaml:"post_start"`
+	PreStop   *Hook `yaml:"pre_stop"`
+	PostStop  *Hook `yaml:"post_stop"`
+	OnFailure *Hook `yaml:"on_failure"`
+}
+
+// hook returns service's hook by name or nil if it isn't defined
+func (s *Service) hook(name string) *Hook {
+	if s.Hooks == nil {
+		return nil
+	}
+
+	switch name {
+	case HOOK_PRE_START:
+		return s.Hooks.PreStart
+	case HOOK_POST_START:
+		return s.Hooks.PostStart
+	case HOOK_PRE_STOP:
+		return s.Hooks.PreStop
+	case HOOK_POST_STOP:
+		return s.Hooks.PostStop
+	case HOOK_ON_FAILURE:
+		return s.Hooks.OnFailure
+	}
+
+	return nil
+}
+
+// reason of service's restart passed to hooks in NANNY_REASON
+func (s *Service) hookReason() string {
+	switch {
+	case s.Disabled:
+		return "disabled"
+	case s.forceRestart:
+		return "force-restart"
+	case len(s.restartReason) > 0:
+		return s.restartReason
+	case s.process == nil:
+		return "not running"
+	default:
+		return "missing instances"
+	}
+}
+
+// runHook executes service's hook if it's defined.
+// Hook gets service's environment, its own 'env_vars' and NANNY_SERVICE, NANNY_HOOK, NANNY_REASON, NANNY_PID.
+// Error of hook is added into s.errorArray and returned.
+func (s *Service) runHook(name string, pid int) error {
+	hook := s.hook(name)
+
+	if hook == nil {
+		return nil
+	}
+
+	err := s.execHook(name, hook, pid)
+
+	if err != nil {
+		level.Error(*s.Logger).Log("msg", "hook failed", "service", s.ProcessName, "hook", name,
+			"error", err.Error())
+
+		s.errorArray = append(s.errorArray, &err)
+	}
+
+	return err
+}
+
+func (s *Service) execHook(name string, hook *Hook, pid int) error {
+	cmd, env, err := s.newCommand(hook.Cmd, nil)
+
+	if err != nil {
+		return &ErrHook{s.ProcessName, name, err.Error(), nil}
+	}
+
+	if err := env.loadList(hook.EnvList); err != nil {
+		return &ErrHook{s.ProcessName, name, fmt.Sprintf("can't interpolate 'env_vars': %s", err.Error()), nil}
+	}
+
+	env.Set("NANNY_SERVICE", s.ProcessName)
+	env.Set("NANNY_HOOK", name)
+	env.Set("NANNY_REASON", s.hookReason())
+
+	if pid > 0 {
+		env.Set("NANNY_PID", strconv.Itoa(pid))
+	}
+
+	cmd.Env = env.List()
+
+	level.Debug(*s.Logger).Log("msg", "execute hook", "service", s.ProcessName, "hook", name,
+		"value", cmd.String(), "timeout", hook.timeout())
+	level.Debug(*s.Logger).Log("msg", "environment variables", "service", s.ProcessName, "hook", name,
+		"value", fmt.Sprintf("%s", env.MaskedList()))
+
+	output, err := s.runCommand(cmd, hook.timeout())
+
+	if err != nil {
+		return &ErrHook{s.ProcessName, name, err.Error(), tailLines(output, s.logTailLines())}
+	}
+
+	level.Debug(*s.Logger).Log("msg", "hook succeeded", "service", s.ProcessName, "hook", name)
+
+	return nil
+}
+
+// last n lines of text
+func tailLines(text string, n int) []string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+
+	if len(lines) == 1 && len(lines[0]) == 0 {
+		return nil
+	}
+
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	return lines
+}
+
+// log hooks which would be executed on restart
+func (s *Service) dryRunHooks(names ...string) {
+	for _, name := range names {
+		if hook := s.hook(name); hook != nil {
+			level.Info(*s.Logger).Log("msg", "dry run. hook would be executed", "service", s.ProcessName,
+				"hook", name, "value", hook.Cmd, "timeout", hook.timeout())
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package checker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreStartHookFailureCancelsStart(t *testing.T) {
+	dir := t.TempDir()
+
+	s := newTestService("hooks-test")
+	s.Shell = shellOption{name: "sh"}
+	s.StartCmd = "touch " + filepath.Join(dir, "started")
+	s.Hooks = &Hooks{
+		PreStart:  &Hook{Cmd: "echo not ready; exit 1"},
+		OnFailure: &Hook{Cmd: "echo $NANNY_SERVICE > " + filepath.Join(dir, "on_failure")},
+	}
+
+	err := s.RestartProcess(false, false)
+
+	var hookErr *ErrHook
+
+	if !errors.As(err, &hookErr) || hookErr.hook != HOOK_PRE_START {
+		t.Fatalf("RestartProcess() error = %v, want error of 'pre_start' hook", err)
+	}
+
+	if len(s.errorArray) != 1 {
+		t.Errorf("RestartProcess() errors = %v, want only error of 'pre_start' hook", s.errorArray)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "started")); err == nil {
+		t.Error("service is started after failed 'pre_start' hook")
+	}
+
+	if content, _ := os.ReadFile(filepath.Join(dir, "on_failure")); string(content) != "hooks-test\n" {
+		t.Errorf("'on_failure' hook output = %q, want service name", content)
+	}
+}
+
+func TestPostStartHookGetsPid(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "post_start")
+
+	s := newTestService("hooks-test")
+	s.Shell = shellOption{name: "sh"}
+	s.StartCmd = "sleep 1"
+	s.EnvList = []string{"HOOK_BASE=value"}
+	s.StartWait = 100 * time.Millisecond
+	s.Hooks = &Hooks{
+		PostStart: &Hook{Cmd: "echo $NANNY_HOOK $NANNY_PID $HOOK_VAR > " + out, EnvList: []string{"HOOK_VAR=hook-${HOOK_BASE}"}},
+	}
+
+	if err := s.RestartProcess(false, false); err != nil {
+		t.Fatalf("RestartProcess() unexpected error: %v", err)
+	}
+
+	content, _ := os.ReadFile(out)
+	fields := strings.Fields(string(content))
+
+	if len(fields) != 3 || fields[0] != HOOK_POST_START || fields[1] == "0" || fields[2] != "hook-value" {
+		t.Errorf("'post_start' hook output = %q, want hook name, pid and hook's env_vars", content)
+	}
+}
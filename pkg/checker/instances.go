@@ -0,0 +1,138 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/go-kit/log/level"
+)
+
+// desired number of service's processes: 'instances' or single process by default
+func (s *Service) instancesCount() int {
+	if s.Instances > 0 {
+		return s.Instances
+	}
+
+	return 1
+}
+
+// number of service's processes which should be started
+func (s *Service) missingInstances() int {
+	if missing := s.instancesCount() - len(s.instances); missing > 0 {
+		return missing
+	}
+
+	return 0
+}
+
+// pids of instances in order of start: the oldest (lowest pid) first
+func (s *Service) instancesPids() []int {
+	var pids []int
+
+	for _, p := range s.instances {
+		pids = append(pids, p.Pid)
+	}
+
+	return pids
+}
+
+// CheckInstances starts missing instances of running service with 'instances' property
+// and reports (or kills with 'kill_excess') instances above desired count.
+// Service without 'instances' is checked for duplicates.
+func (s *Service) CheckInstances(dryRun bool) error {
+	if s.Disabled {
+		return nil
+	}
+
+	s.dryRun = dryRun
+
+	if s.Instances == 0 {
+		return s.checkDuplicates()
+	}
+
+	if len(s.instances) > s.instancesCount() {
+		return s.checkExcess()
+	}
+
+	missing := s.missingInstances()
+
+	if missing == 0 {
+		return nil
+	}
+
+	level.Warn(*s.Logger).Log("msg", "service has missing instances", "service", s.ProcessName,
+		"value", fmt.Sprintf("%d/%d", len(s.instances), s.instancesCount()))
+
+	if s.dryRun {
+		level.Info(*s.Logger).Log("msg", "dry run. missing instances would be started",
+			"service", s.ProcessName, "value", missing)
+
+		return nil
+	}
+
+	if err := s.checkWorkingDir(); err != nil {
+		s.failState = STATE_RESTART_FAILED
+
+		return err
+	}
+
+	return s.start()
+}
+
+// checkExcess reports instances above desired count and kills the newest ones with 'kill_excess'
+func (s *Service) checkExcess() error {
+	var err error = &ErrExcessInstances{s.ProcessName, s.instancesCount(), s.instancesPids()}
+
+	level.Warn(*s.Logger).Log("msg", "service has excess instances", "service", s.ProcessName,
+		"error", err.Error())
+
+	s.errorArray = append(s.errorArray, &err)
+
+	if s.KillExcess {
+		s.killInstances(s.instances[s.instancesCount():], "excess instance")
+	}
+
+	return nil
+}
+
+// checkDuplicates reports processes of single instance service started more than once
+// and kills all of them except the oldest one with 'kill_duplicates'
+func (s *Service) checkDuplicates() error {
+	if len(s.instances) < 2 {
+		return nil
+	}
+
+	var err error = &ErrDuplicateProcess{s.ProcessName, s.instances}
+
+	level.Warn(*s.Logger).Log("msg", "service has duplicate processes", "service", s.ProcessName,
+		"error", err.Error())
+
+	s.errorArray = append(s.errorArray, &err)
+
+	if s.KillDupes {
+		s.killInstances(s.instances[1:], "duplicate process")
+	}
+
+	return nil
+}
+
+// killInstances kills given service's processes according to 'kill_mode'
+func (s *Service) killInstances(processes []*Process, kind string) {
+	for _, p := range processes {
+		if s.dryRun {
+			level.Info(*s.Logger).Log("msg", fmt.Sprintf("dry run. %s would be killed", kind),
+				"service", s.ProcessName, "value", p.Pid, "kill_mode", s.killMode())
+
+			continue
+		}
+
+		level.Info(*s.Logger).Log("msg", fmt.Sprintf("kill %s", kind), "service", s.ProcessName,
+			"value", p.Pid, "kill_mode", s.killMode())
+
+		if err := s.killProcess(p.Pid); err != nil {
+			level.Error(*s.Logger).Log("msg", fmt.Sprintf("got error when try to kill %s", kind),
+				"service", s.ProcessName, "value", p.Pid, "error", err.Error())
+
+			s.errorArray = append(s.errorArray, &err)
+		}
+	}
+}
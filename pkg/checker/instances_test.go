@@ -0,0 +1,103 @@
+package checker
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// startTestProcesses starts n sleeping processes which are killed at the end of test
+func startTestProcesses(t *testing.T, n int) []*Process {
+	var processes []*Process
+
+	for i := 0; i < n; i++ {
+		cmd := exec.Command("sleep", "30")
+
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		go cmd.Wait()
+
+		t.Cleanup(func() { cmd.Process.Kill() })
+
+		processes = append(processes, &Process{Pid: cmd.Process.Pid, PPid: 1})
+	}
+
+	return processes
+}
+
+func TestMissingInstances(t *testing.T) {
+	s := newTestService("instances-test")
+	s.instances = []*Process{{Pid: 10}}
+
+	if got := s.missingInstances(); got != 0 {
+		t.Errorf("missingInstances() without 'instances' = %d, want 0", got)
+	}
+
+	s.Instances = 3
+
+	if got := s.missingInstances(); got != 2 {
+		t.Errorf("missingInstances() = %d, want 2", got)
+	}
+}
+
+func TestKillExcessInstances(t *testing.T) {
+	for _, mode := range []string{KILL_MODE_PROCESS, KILL_MODE_CGROUP} {
+		s := newTestService("instances-test")
+		s.Instances = 1
+		s.KillExcess = true
+		s.KillMode = killMode(mode)
+		s.instances = startTestProcesses(t, 3)
+
+		processesList = make(map[int]*Process)
+		buildProcessesTree()
+
+		s.CheckInstances(false)
+
+		var excessErr *ErrExcessInstances
+
+		if len(s.errorArray) != 1 || !errors.As(*s.errorArray[0], &excessErr) {
+			t.Errorf("kill_mode %s: CheckInstances() errors = %v, want only excess instances", mode, s.errorArray)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+		if !processAlive(s.instances[0].Pid) {
+			t.Errorf("kill_mode %s: the oldest instance is killed", mode)
+		}
+
+		for _, p := range s.instances[1:] {
+			if processAlive(p.Pid) {
+				t.Errorf("kill_mode %s: excess instance %d is alive", mode, p.Pid)
+			}
+		}
+	}
+}
+
+func TestKillDuplicates(t *testing.T) {
+	for _, mode := range []string{KILL_MODE_TREE, KILL_MODE_CGROUP} {
+		s := newTestService("duplicates-test")
+		s.KillDupes = true
+		s.KillMode = killMode(mode)
+		s.instances = startTestProcesses(t, 2)
+
+		processesList = make(map[int]*Process)
+		buildProcessesTree()
+
+		s.CheckInstances(false)
+
+		var duplicateErr *ErrDuplicateProcess
+
+		if len(s.errorArray) != 1 || !errors.As(*s.errorArray[0], &duplicateErr) {
+			t.Errorf("kill_mode %s: CheckInstances() errors = %v, want only duplicate processes", mode, s.errorArray)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+		if !processAlive(s.instances[0].Pid) || processAlive(s.instances[1].Pid) {
+			t.Errorf("kill_mode %s: duplicate processes %v aren't killed except the oldest one", mode, s.instancesPids())
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package checker
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/go-kit/log/level"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RLIM_INFINITY      uint64 = math.MaxUint64
+	IOPRIO_WHO_PROCESS int    = 1
+	IOPRIO_CLASS_SHIFT int    = 13
+	// first argument of nanny started as helper which applies limits to itself and executes service's command
+	LIMITS_EXEC_ARG string = "__exec-with-limits"
+	// exit code of helper which can't execute command, like in env(1)
+	LIMITS_EXEC_FAILURE int = 125
+)
+
+type rlimitResource struct {
+	id int
+	// row name in /proc/<pid>/limits
+	procName string
+}
+
+// supported names of 'rlimits' keys like in prlimit(1)
+var rlimitResources = map[string]rlimitResource{
+	"cpu":        {0, "Max cpu time"},
+	"fsize":      {1, "Max file size"},
+	"data":       {2, "Max data size"},
+	"stack":      {3, "Max stack size"},
+	"core":       {4, "Max core file size"},
+	"rss":        {5, "Max resident set"},
+	"nproc":      {6, "Max processes"},
+	"nofile":     {7, "Max open files"},
+	"memlock":    {8, "Max locked memory"},
+	"as":         {9, "Max address space"},
+	"locks":      {10, "Max file locks"},
+	"sigpending": {11, "Max pending signals"},
+	"msgqueue":   {12, "Max msgqueue size"},
+	"nice":       {13, "Max nice priority"},
+	"rtprio":     {14, "Max realtime priority"},
+	"rttime":     {15, "Max realtime timeout"},
+}
+
+// supported values of 'ionice_class' like in ionice(1)
+var ioniceClasses = map[string]int{
+	"realtime":    1,
+	"best-effort": 2,
+	"idle":        3,
+}
+
+// rlimitValue is 'rlimits' value: "<soft>:<hard>" or single value for both limits.
+// "unlimited" means no limit.
+type rlimitValue struct {
+	soft uint64
+	hard uint64
+}
+
+func parseRlimit(value string) (uint64, error) {
+	value = strings.TrimSpace(value)
+
+	if value == "unlimited" || value == "infinity" {
+		return RLIM_INFINITY, nil
+	}
+
+	return strconv.ParseUint(value, 10, 64)
+}
+
+func (v *rlimitValue) UnmarshalYAML(value *yaml.Node) error {
+	var err error
+
+	soft, hard, found := strings.Cut(value.Value, ":")
+
+	if v.soft, err = parseRlimit(soft); err != nil {
+		return fmt.Errorf("line %d: invalid rlimit value '%s'", value.Line, value.Value)
+	}
+
+	v.hard = v.soft
+
+	if found {
+		if v.hard, err = parseRlimit(hard); err != nil {
+			return fmt.Errorf("line %d: invalid rlimit value '%s'", value.Line, value.Value)
+		}
+	}
+
+	return nil
+}
+
+// rlimits is 'rlimits' property of service with validated resource names
+type rlimits map[string]rlimitValue
+
+func (r *rlimits) UnmarshalYAML(value *yaml.Node) error {
+	var limits map[string]rlimitValue
+
+	if err := value.Decode(&limits); err != nil {
+		return err
+	}
+
+	for name := range limits {
+		if _, ok := rlimitResources[name]; !ok {
+			return fmt.Errorf("line %d: unsupported rlimit '%s'", value.Line, name)
+		}
+	}
+
+	*r = limits
+
+	return nil
+}
+
+// sorted names of configured limits
+func (r rlimits) names() []string {
+	var names []string
+
+	for name := range r {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func ioprioSet(class int, data int) error {
+	// pid 0 is calling thread
+	_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, uintptr(IOPRIO_WHO_PROCESS), 0,
+		uintptr(class<<IOPRIO_CLASS_SHIFT|data))
+
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// execLimits is limits and credential of service passed to limits helper
+type execLimits struct {
+	Rlimits     map[string]syscall.Rlimit `json:"rlimits,omitempty"`
+	Nice        *int                      `json:"nice,omitempty"`
+	IONiceClass string                    `json:"ionice_class,omitempty"`
+	IONiceLevel int                       `json:"ionice_level,omitempty"`
+	OOMScoreAdj *int                      `json:"oom_score_adj,omitempty"`
+	Credential  *syscall.Credential       `json:"credential,omitempty"`
+	// write end of pipe which receives errors of limits
+	ErrorFd int `json:"error_fd"`
+}
+
+func (s *Service) hasLimits() bool {
+	return len(s.Rlimits) > 0 || s.Nice != nil || len(s.IONiceClass) > 0 || s.OOMScoreAdj != nil
+}
+
+// withLimits replaces command by nanny's own binary started as limits helper.
+// Helper applies 'rlimits', 'nice', 'ionice_class', 'ionice_level' and 'oom_score_adj' to itself,
+// switches credential and executes command, so service's process runs with its limits from the first instruction.
+// Returns read end of pipe which receives errors of limits or nil if service doesn't have limits.
+func (s *Service) withLimits(cmd *exec.Cmd) (*os.File, error) {
+	if !s.hasLimits() {
+		return nil, nil
+	}
+
+	self, err := os.Executable()
+
+	if err != nil {
+		return nil, err
+	}
+
+	limits := &execLimits{
+		Rlimits:     make(map[string]syscall.Rlimit),
+		Nice:        s.Nice,
+		IONiceClass: s.IONiceClass,
+		OOMScoreAdj: s.OOMScoreAdj,
+	}
+
+	for name, value := range s.Rlimits {
+		limits.Rlimits[name] = syscall.Rlimit{Cur: value.soft, Max: value.hard}
+	}
+
+	if s.IONiceLevel != nil {
+		limits.IONiceLevel = *s.IONiceLevel
+	}
+
+	// credential is switched after limits, so unprivileged service can get limits which only root can set
+	if cmd.SysProcAttr != nil && cmd.SysProcAttr.Credential != nil {
+		limits.Credential = cmd.SysProcAttr.Credential
+		cmd.SysProcAttr.Credential = nil
+	}
+
+	errReader, errWriter, err := os.Pipe()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// ExtraFiles[i] is descriptor 3+i of started process
+	limits.ErrorFd = 3 + len(cmd.ExtraFiles)
+	cmd.ExtraFiles = append(cmd.ExtraFiles, errWriter)
+
+	data, err := json.Marshal(limits)
+
+	if err != nil {
+		closeFiles([]*os.File{errReader, errWriter})
+
+		return nil, err
+	}
+
+	level.Debug(*s.Logger).Log("msg", "start command by limits helper", "service", s.ProcessName,
+		"value", string(data))
+
+	cmd.Args = append([]string{self, LIMITS_EXEC_ARG, string(data), cmd.Path}, cmd.Args...)
+	cmd.Path = self
+
+	return errReader, nil
+}
+
+// readLimitErrors returns errors reported by limits helper.
+// Pipe is closed when helper executes command or exits.
+func readLimitErrors(r *os.File) []string {
+	var errs []string
+
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		errs = append(errs, scanner.Text())
+	}
+
+	return errs
+}
+
+// apply sets limits to current process and returns errors of limits which can't be set
+func (l *execLimits) apply() []error {
+	var errs []error
+
+	names := make([]string, 0, len(l.Rlimits))
+
+	for name := range l.Rlimits {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		limit := l.Rlimits[name]
+
+		// syscall.Setrlimit is used instead of prlimit(2), so Go runtime doesn't restore
+		// its original 'nofile' limit on exec
+		if err := syscall.Setrlimit(rlimitResources[name].id, &limit); err != nil {
+			errs = append(errs, fmt.Errorf("can't set rlimit '%s': %w", name, err))
+		}
+	}
+
+	if l.Nice != nil {
+		// who 0 is calling thread
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, *l.Nice); err != nil {
+			errs = append(errs, fmt.Errorf("can't set nice %d: %w", *l.Nice, err))
+		}
+	}
+
+	if len(l.IONiceClass) > 0 {
+		if err := ioprioSet(ioniceClasses[l.IONiceClass], l.IONiceLevel); err != nil {
+			errs = append(errs, fmt.Errorf("can't set ionice class '%s': %w", l.IONiceClass, err))
+		}
+	}
+
+	if l.OOMScoreAdj != nil {
+		if err := os.WriteFile("/proc/self/oom_score_adj", []byte(strconv.Itoa(*l.OOMScoreAdj)), 0644); err != nil {
+			errs = append(errs, fmt.Errorf("can't set oom_score_adj %d: %w", *l.OOMScoreAdj, err))
+		}
+	}
+
+	return errs
+}
+
+// switchCredential sets groups, gid and uid like fork/exec with syscall.Credential
+func (l *execLimits) switchCredential() error {
+	cred := l.Credential
+
+	if cred == nil {
+		return nil
+	}
+
+	if !cred.NoSetGroups {
+		groups := make([]int, len(cred.Groups))
+
+		for i, gid := range cred.Groups {
+			groups[i] = int(gid)
+		}
+
+		if err := syscall.Setgroups(groups); err != nil {
+			return fmt.Errorf("can't set groups %v: %w", cred.Groups, err)
+		}
+	}
+
+	if err := syscall.Setgid(int(cred.Gid)); err != nil {
+		return fmt.Errorf("can't set gid %d: %w", cred.Gid, err)
+	}
+
+	if err := syscall.Setuid(int(cred.Uid)); err != nil {
+		return fmt.Errorf("can't set uid %d: %w", cred.Uid, err)
+	}
+
+	return nil
+}
+
+// ExecWithLimits is entry point of limits helper started with LIMITS_EXEC_ARG.
+// Args are limits in JSON, path of command and command's arguments.
+// Errors of limits are written into nanny's pipe and command is executed without them.
+// If credential can't be switched or command can't be executed, helper exits with LIMITS_EXEC_FAILURE.
+func ExecWithLimits(args []string) {
+	var limits execLimits
+
+	// nice and I/O priority are attributes of thread, so they are set by thread which executes command
+	runtime.LockOSThread()
+
+	if len(args) < 3 {
+		execFailed(fmt.Errorf("usage: %s <limits> <path> <args>...", LIMITS_EXEC_ARG))
+	}
+
+	if err := json.Unmarshal([]byte(args[0]), &limits); err != nil {
+		execFailed(fmt.Errorf("can't parse limits: %w", err))
+	}
+
+	errPipe := os.NewFile(uintptr(limits.ErrorFd), "limits-errors")
+
+	// service doesn't inherit pipe
+	syscall.CloseOnExec(limits.ErrorFd)
+
+	for _, err := range limits.apply() {
+		fmt.Fprintln(errPipe, err.Error())
+	}
+
+	if err := limits.switchCredential(); err != nil {
+		execFailed(err)
+	}
+
+	execFailed(syscall.Exec(args[1], args[2:], os.Environ()))
+}
+
+func execFailed(err error) {
+	fmt.Fprintf(os.Stderr, "autosys-nanny: can't execute service's command: %s\n", err.Error())
+	os.Exit(LIMITS_EXEC_FAILURE)
+}
+
+// checkLimits validates values which can't be validated by YAML decoder
+func (s *Service) checkLimits() error {
+	if len(s.IONiceClass) > 0 {
+		if _, ok := ioniceClasses[s.IONiceClass]; !ok {
+			return fmt.Errorf("unsupported 'ionice_class' '%s'. supported values: realtime, best-effort, idle", s.IONiceClass)
+		}
+	}
+
+	if s.IONiceLevel != nil && (*s.IONiceLevel < 0 || *s.IONiceLevel > 7) {
+		return fmt.Errorf("'ionice_level' should be in range 0..7")
+	}
+
+	if s.Nice != nil && (*s.Nice < -20 || *s.Nice > 19) {
+		return fmt.Errorf("'nice' should be in range -20..19")
+	}
+
+	if s.OOMScoreAdj != nil && (*s.OOMScoreAdj < -1000 || *s.OOMScoreAdj > 1000) {
+		return fmt.Errorf("'oom_score_adj' should be in range -1000..1000")
+	}
+
+	return nil
+}
+
+// effectiveLimits returns configured limits of process read back from /proc/<pid>/limits
+// in "<name>=<soft>/<hard>" format
+func (s *Service) effectiveLimits(pid int) string {
+	var limits []string
+
+	if len(s.Rlimits) == 0 {
+		return "-"
+	}
+
+	f, err := os.Open(fmt.Sprintf("/proc/%d/limits", pid))
+
+	if err != nil {
+		return "null"
+	}
+
+	defer f.Close()
+
+	procLimits := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		for name, resource := range rlimitResources {
+			if values, ok := strings.CutPrefix(line, resource.procName); ok {
+				// columns: soft limit, hard limit, units
+				fields := strings.Fields(values)
+
+				if len(fields) >= 2 {
+					procLimits[name] = fmt.Sprintf("%s/%s", fields[0], fields[1])
+				}
+			}
+		}
+	}
+
+	for _, name := range s.Rlimits.names() {
+		limits = append(limits, fmt.Sprintf("%s=%s", name, procLimits[name]))
+	}
+
+	return strings.Join(limits, ",")
+}
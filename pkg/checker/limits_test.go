@@ -0,0 +1,68 @@
+package checker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartWithLimits(t *testing.T) {
+	for _, doubleFork := range []bool{false, true} {
+		out := filepath.Join(t.TempDir(), "limits")
+		nice := 5
+
+		s := newTestService("limits-test")
+		s.Shell = shellOption{direct: true}
+		s.StartCmd = "/bin/sh"
+		// nice is the 19th field of /proc/<pid>/stat
+		s.CmdArgs = []string{"-c", "ulimit -Sn > " + out + "; cut -d' ' -f19 /proc/$$/stat >> " + out + "; sleep 1"}
+		s.StartWait = 200 * time.Millisecond
+		s.DoubleFork = doubleFork
+		s.Nice = &nice
+		s.Rlimits = rlimits{
+			"nofile": {soft: 64, hard: 1024},
+			// soft limit above hard one is always rejected
+			"core": {soft: 2048, hard: 1024},
+		}
+
+		s.start()
+
+		var limitsErr *ErrLimits
+		var reported int
+
+		for _, e := range s.errorArray {
+			if errors.As(*e, &limitsErr) {
+				reported++
+
+				if !strings.Contains(limitsErr.message, "core") {
+					t.Errorf("double_fork %t: unexpected limits error: %v", doubleFork, limitsErr)
+				}
+			}
+		}
+
+		if reported != 1 {
+			t.Errorf("double_fork %t: reported %d limits errors, want 1: %v", doubleFork, reported, s.errorArray)
+		}
+
+		if failure := startFailure(s); failure != nil {
+			t.Fatalf("double_fork %t: start failed: %v", doubleFork, failure)
+		}
+
+		var got string
+
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			if data, _ := os.ReadFile(out); strings.Count(string(data), "\n") == 2 {
+				got = string(data)
+
+				break
+			}
+		}
+
+		if got != "64\n5\n" {
+			t.Errorf("double_fork %t: service started with nofile and nice %q, want %q", doubleFork, got, "64\n5\n")
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package checker
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+
+	"github.com/go-kit/log/level"
+	"gopkg.in/yaml.v3"
+
+	npf "github.com/ashokhin/autosys-nanny/pkg/file"
+)
+
+const (
+	LOG_DEFAULT_MAX_SIZE    int64 = 10 * 1024 * 1024
+	LOG_DEFAULT_MAX_BACKUPS int   = 5
+	LOG_DEFAULT_TAIL_LINES  int   = 20
+)
+
+// byteSize is size property in "512", "100K", "10MB" or "1G" format
+type byteSize int64
+
+func (b *byteSize) UnmarshalYAML(value *yaml.Node) error {
+	size, err := npf.ParseSize(value.Value)
+
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+
+	*b = byteSize(size)
+
+	return nil
+}
+
+// file for service's stdout: 'stdout_log' or combined 'log_file'
+func (s *Service) stdoutPath() string {
+	if len(s.StdoutLog) > 0 {
+		return s.resolvePath(s.StdoutLog)
+	}
+
+	return s.resolvePath(s.LogFile)
+}
+
+// file for service's stderr: 'stderr_log' or combined 'log_file'
+func (s *Service) stderrPath() string {
+	if len(s.StderrLog) > 0 {
+		return s.resolvePath(s.StderrLog)
+	}
+
+	return s.resolvePath(s.LogFile)
+}
+
+func (s *Service) logMaxSize() int64 {
+	if s.LogMaxSize > 0 {
+		return int64(s.LogMaxSize)
+	}
+
+	return LOG_DEFAULT_MAX_SIZE
+}
+
+func (s *Service) logMaxBackups() int {
+	if s.LogBackups > 0 {
+		return s.LogBackups
+	}
+
+	return LOG_DEFAULT_MAX_BACKUPS
+}
+
+func (s *Service) logTailLines() int {
+	if s.LogTailLines > 0 {
+		return s.LogTailLines
+	}
+
+	return LOG_DEFAULT_TAIL_LINES
+}
+
+// rotate service's log files which exceeded 'log_max_size'
+func (s *Service) rotateLogs() {
+	for _, logPath := range uniqueStrings(s.stdoutPath(), s.stderrPath()) {
+		rotated, err := npf.RotateFile(logPath, s.logMaxSize(), s.logMaxBackups())
+
+		if err != nil {
+			level.Error(*s.Logger).Log("msg", "got error when try to rotate log file",
+				"service", s.ProcessName, "value", logPath, "error", err.Error())
+
+			s.errorArray = append(s.errorArray, &err)
+
+			continue
+		}
+
+		if rotated {
+			level.Info(*s.Logger).Log("msg", "log file rotated", "service", s.ProcessName, "value", logPath)
+		}
+	}
+}
+
+// openLogs redirects command's stdout and stderr into service's log files.
+// Returned files should be closed by caller after command start.
+func (s *Service) openLogs(cmd *exec.Cmd) ([]*os.File, error) {
+	var files []*os.File
+
+	for _, logPath := range uniqueStrings(s.stdoutPath(), s.stderrPath()) {
+		f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+
+		if err != nil {
+			closeFiles(files)
+
+			return nil, err
+		}
+
+		files = append(files, f)
+
+		if logPath == s.stdoutPath() {
+			cmd.Stdout = f
+		}
+
+		if logPath == s.stderrPath() {
+			cmd.Stderr = f
+
+			// output of previous runs isn't reported as output of this start
+			if fstat, err := f.Stat(); err == nil {
+				s.stderrOffset = fstat.Size()
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// last lines of service's stderr log
+func (s *Service) stderrTail() []string {
+	return s.stderrTailFrom(0)
+}
+
+// last lines of service's stderr log written by the last started process
+func (s *Service) startStderrTail() []string {
+	return s.stderrTailFrom(s.stderrOffset)
+}
+
+func (s *Service) stderrTailFrom(offset int64) []string {
+	if len(s.stderrPath()) == 0 {
+		return nil
+	}
+
+	lines, err := npf.TailFileFrom(s.stderrPath(), offset, s.logTailLines())
+
+	if err != nil {
+		level.Warn(*s.Logger).Log("msg", "can't read stderr log", "service", s.ProcessName,
+			"value", s.stderrPath(), "error", err.Error())
+	}
+
+	return lines
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// non-empty unique values in original order
+func uniqueStrings(values ...string) []string {
+	var result []string
+
+	for _, v := range values {
+		if len(v) == 0 {
+			continue
+		}
+
+		if !slices.Contains(result, v) {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,70 @@
+package checker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// startFailure returns ErrStartFailed reported by the last start of service
+func startFailure(s *Service) *ErrStartFailed {
+	var startErr *ErrStartFailed
+
+	for _, e := range s.errorArray {
+		if errors.As(*e, &startErr) {
+			return startErr
+		}
+	}
+
+	return nil
+}
+
+func TestStartFailedReportsStderrTail(t *testing.T) {
+	stderrLog := filepath.Join(t.TempDir(), "service.err")
+
+	if err := os.WriteFile(stderrLog, []byte("output of previous run\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestService("logs-test")
+	s.Shell = shellOption{name: "sh"}
+	s.StartCmd = "echo first >&2; echo second >&2; exit 3"
+	s.StderrLog = stderrLog
+	s.StartWait = 2 * time.Second
+
+	s.start()
+
+	startErr := startFailure(s)
+
+	if startErr == nil {
+		t.Fatalf("start() errors = %v, want ErrStartFailed", s.errorArray)
+	}
+
+	if startErr.exitStatus != "exit status 3" {
+		t.Errorf("exit status = %q, want %q", startErr.exitStatus, "exit status 3")
+	}
+
+	if want := []string{"first", "second"}; !slices.Equal(startErr.stderrTail, want) {
+		t.Errorf("stderr tail = %q, want %q", startErr.stderrTail, want)
+	}
+}
+
+func TestStartWaitPassed(t *testing.T) {
+	s := newTestService("logs-test")
+	s.Shell = shellOption{name: "sh"}
+	s.StartCmd = "sleep 1"
+	s.StartWait = 100 * time.Millisecond
+
+	s.start()
+
+	if startErr := startFailure(s); startErr != nil {
+		t.Fatalf("start() unexpected error: %v", startErr)
+	}
+
+	if len(s.errorArray) != 1 {
+		t.Errorf("start() errors = %v, want only start notification", s.errorArray)
+	}
+}
@@ -0,0 +1,122 @@
+package checker
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-kit/log/level"
+
+	"github.com/ashokhin/autosys-nanny/pkg/notifier"
+)
+
+const NOTIFY_TEST_SERVICE string = "nanny-notify-test"
+
+// notifyTestMessage returns sample notification about restarted service
+func (c *Checker) notifyTestMessage(now time.Time) *notifier.Message {
+	sample := fmt.Errorf("service '%s' was restarted. This is test notification sent by 'notify-test' command",
+		NOTIFY_TEST_SERVICE)
+
+	msg := &notifier.Message{
+		Hostname:     c.hostname,
+		Service:      NOTIFY_TEST_SERVICE,
+		Description:  "Test notification",
+		State:        STATE_RESTARTED,
+		Subject:      fmt.Sprintf("%s | '%s' alert - restarted", c.subjectPrefix, NOTIFY_TEST_SERVICE),
+		Errors:       []*error{&sample},
+		Time:         now,
+		RestartCount: 1,
+		Process: &notifier.ProcessInfo{
+			Pid:       os.Getpid(),
+			PPid:      os.Getppid(),
+			Cmdline:   NOTIFY_TEST_SERVICE,
+			StartTime: now,
+		},
+		LogTail: []string{"sample line of service's log"},
+	}
+
+	c.renderSubject(msg)
+
+	return msg
+}
+
+// NotifyTest sends sample notification by every notifier from 'notifiers' section
+// and by email to 'to' or to general 'mailing_list'. Result of every notifier is printed.
+func (c *Checker) NotifyTest(to []string) error {
+	if err := c.loadYaml(); err != nil {
+		return err
+	}
+
+	var err error
+
+	if c.hostname, err = os.Hostname(); err != nil {
+		return err
+	}
+
+	c.setupNotifiers()
+
+	if len(to) == 0 && c.Config.Mailer.Headers != nil {
+		to = c.Config.Mailer.Headers.To
+	}
+
+	type backend struct {
+		kind string
+		n    notifier.Notifier
+	}
+
+	var backends []backend
+
+	if len(to) > 0 {
+		backends = append(backends, backend{notifier.TYPE_SMTP, notifier.NewSmtp("mailing_list", c.Config.Mailer, c.templates, to)})
+	}
+
+	for _, config := range c.Config.Notifiers {
+		backends = append(backends, backend{config.Type, c.notifiers[config.Name]})
+	}
+
+	if len(backends) == 0 {
+		err := fmt.Errorf("'Nanny' script error: config doesn't have general 'mailing_list' or 'notifiers'. use '--to' for test email")
+		c.AllErrorsArray = append(c.AllErrorsArray, &err)
+
+		return err
+	}
+
+	// settings are checked once, so error isn't hidden among errors of smtp notifiers
+	if err := c.Config.Mailer.CheckSettings(); err != nil {
+		level.Warn(*c.logger).Log("msg", "mail settings are invalid. emails can't be sent", "error", err.Error())
+	} else {
+		level.Info(*c.logger).Log("msg", "mail settings are valid", "server", c.Config.Mailer.SmtpServer)
+	}
+
+	msg := c.notifyTestMessage(time.Now())
+	failed := 0
+
+	w := tabwriter.NewWriter(os.Stdout, 4, 4, 4, ' ', tabwriter.TabIndent|tabwriter.Debug)
+	fmt.Fprintln(w, "Notifier\tType\tResult\tError")
+
+	for _, b := range backends {
+		level.Info(*c.logger).Log("msg", "send test notification", "notifier", b.n.Name(), "value", msg.Subject)
+
+		if err := b.n.Notify(msg); err != nil {
+			level.Error(*c.logger).Log("msg", "got error when try to send test notification",
+				"notifier", b.n.Name(), "error", err.Error())
+
+			failed++
+			c.AllErrorsArray = append(c.AllErrorsArray, &err)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", b.n.Name(), b.kind, "failed", err.Error())
+
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", b.n.Name(), b.kind, "sent", "")
+	}
+
+	w.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d notifiers failed", failed, len(backends))
+	}
+
+	return nil
+}
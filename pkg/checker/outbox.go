@@ -0,0 +1,218 @@
+package checker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+
+	"github.com/ashokhin/autosys-nanny/pkg/notifier"
+)
+
+const OUTBOX_DEFAULT_MAX_AGE time.Duration = 24 * time.Hour
+
+// outboxEntry is notification which failed to send, stored in outbox for retries
+type outboxEntry struct {
+	Created   time.Time `json:"created"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	// name of notifier from 'notifiers' section
+	Notifier string `json:"notifier"`
+	// recipients of email to service's or general 'mailing_list'
+	MailList []string          `json:"mailing_list,omitempty"`
+	Message  *notifier.Message `json:"message"`
+}
+
+func (e *outboxEntry) String() string {
+	return fmt.Sprintf("%+v", *e)
+}
+
+func (c *CheckerConfig) outboxMaxAge() time.Duration {
+	if c.alerting().OutboxMaxAge <= 0 {
+		return OUTBOX_DEFAULT_MAX_AGE
+	}
+
+	return c.Alerting.OutboxMaxAge
+}
+
+// outboxDir returns directory of notifications which failed to send
+func (c *Checker) outboxDir() string {
+	return filepath.Join(c.StateDir, "outbox", c.stateName())
+}
+
+// writeOutboxEntry atomically writes entry into outbox file
+func (c *Checker) writeOutboxEntry(path string, entry *outboxEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0640); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// spool stores notification which failed to send into outbox
+func (c *Checker) spool(msg *notifier.Message, n notifier.Notifier, sendErr error) {
+	entry := &outboxEntry{
+		Created:   time.Now(),
+		Attempts:  1,
+		LastError: sendErr.Error(),
+		Notifier:  n.Name(),
+		Message:   msg,
+	}
+
+	// emails to 'mailing_list' aren't notifiers from config, so their recipients are stored
+	if _, ok := c.notifiers[n.Name()]; !ok {
+		smtp, ok := n.(*notifier.Smtp)
+
+		if !ok {
+			return
+		}
+
+		entry.MailList = smtp.Recipients()
+	}
+
+	if err := os.MkdirAll(c.outboxDir(), 0750); err != nil {
+		level.Error(*c.logger).Log("msg", "got error when try to create outbox directory",
+			"value", c.outboxDir(), "error", err.Error())
+
+		return
+	}
+
+	// notifier's name is used in file name only for convenience
+	path := filepath.Join(c.outboxDir(), fmt.Sprintf("%d-%s.json", entry.Created.UnixNano(), safeFileName(n.Name())))
+
+	if err := c.writeOutboxEntry(path, entry); err != nil {
+		level.Error(*c.logger).Log("msg", "got error when try to write notification into outbox",
+			"value", path, "error", err.Error())
+
+		return
+	}
+
+	level.Info(*c.logger).Log("msg", "notification is stored in outbox for retry", "notifier", n.Name(),
+		"service", msg.Service, "value", path)
+}
+
+// safeFileName replaces path separators and spaces in name
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == os.PathSeparator || r == ' ' {
+			return '_'
+		}
+
+		return r
+	}, name)
+}
+
+// outboxNotifier returns notifier of outbox entry or nil if notifier was removed from config
+func (c *Checker) outboxNotifier(entry *outboxEntry) notifier.Notifier {
+	if len(entry.MailList) > 0 {
+		return notifier.NewSmtp(entry.Notifier, c.Config.Mailer, c.templates, entry.MailList)
+	}
+
+	if n, ok := c.notifiers[entry.Notifier]; ok {
+		return n
+	}
+
+	return nil
+}
+
+// retryOutbox sends notifications from outbox. Sent notifications and notifications
+// older than 'outbox_max_age' are removed, other ones are kept for the next run.
+func (c *Checker) retryOutbox(now time.Time) {
+	// names start with creation time, so notifications are retried in original order
+	matches, _ := filepath.Glob(filepath.Join(c.outboxDir(), "*.json"))
+
+	// notifier which has failed isn't retried until the next run
+	failed := make(map[string]bool)
+
+	for _, path := range matches {
+		var entry outboxEntry
+
+		data, err := os.ReadFile(path)
+
+		if err == nil {
+			err = json.Unmarshal(data, &entry)
+		}
+
+		if err != nil || entry.Message == nil {
+			level.Error(*c.logger).Log("msg", "remove broken notification from outbox", "value", path)
+
+			os.Remove(path)
+
+			continue
+		}
+
+		n := c.outboxNotifier(&entry)
+
+		if n == nil {
+			level.Warn(*c.logger).Log("msg", "remove notification of unknown notifier from outbox",
+				"notifier", entry.Notifier, "value", path)
+
+			os.Remove(path)
+
+			continue
+		}
+
+		if now.Sub(entry.Created) > c.Config.outboxMaxAge() {
+			err := fmt.Errorf("'Nanny' script error: notification '%s' by notifier '%s' wasn't sent within %s and is dropped. Last error: %s",
+				entry.Message.Subject, entry.Notifier, c.Config.outboxMaxAge(), entry.LastError)
+
+			level.Error(*c.logger).Log("msg", "drop expired notification from outbox", "notifier", entry.Notifier,
+				"value", path, "error", err.Error())
+
+			c.checkerErrorArray = append(c.checkerErrorArray, &err)
+			os.Remove(path)
+
+			continue
+		}
+
+		if failed[entry.Notifier] {
+			level.Debug(*c.logger).Log("msg", "notifier has failed in this run. skip retry of notification from outbox",
+				"notifier", entry.Notifier, "value", path)
+
+			continue
+		}
+
+		level.Debug(*c.logger).Log("msg", "retry notification from outbox", "notifier", entry.Notifier,
+			"value", entry.Message.Subject, "attempts", entry.Attempts)
+
+		if err := n.Notify(entry.Message); err != nil {
+			failed[entry.Notifier] = true
+			entry.Attempts++
+			entry.LastError = err.Error()
+
+			level.Warn(*c.logger).Log("msg", "got error when try to send notification from outbox",
+				"notifier", entry.Notifier, "value", path, "attempts", entry.Attempts, "error", err.Error())
+
+			if err := c.writeOutboxEntry(path, &entry); err != nil {
+				level.Error(*c.logger).Log("msg", "got error when try to update notification in outbox",
+					"value", path, "error", err.Error())
+			}
+
+			continue
+		}
+
+		level.Info(*c.logger).Log("msg", "notification from outbox is sent", "notifier", entry.Notifier,
+			"value", strings.TrimSuffix(filepath.Base(path), ".json"), "attempts", entry.Attempts+1)
+
+		os.Remove(path)
+
+		// delivered notification delays reminders of services
+		c.notified(entry.Message.Service, now)
+
+		for _, m := range entry.Message.Digest {
+			c.notified(m.Service, now)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package checker
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/ashokhin/autosys-nanny/pkg/notifier"
+)
+
+// outboxEntries returns entries of outbox in order of retries
+func outboxEntries(t *testing.T, c *Checker) []*outboxEntry {
+	t.Helper()
+
+	matches, _ := filepath.Glob(filepath.Join(c.outboxDir(), "*.json"))
+
+	var entries []*outboxEntry
+
+	for _, path := range matches {
+		data, err := os.ReadFile(path)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entry := new(outboxEntry)
+
+		if err := json.Unmarshal(data, entry); err != nil {
+			t.Fatalf("outbox file %s: %v", path, err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// newOutboxChecker returns checker with file notifier which fails until directory of its file is created
+func newOutboxChecker(t *testing.T) (*Checker, notifier.Notifier, string) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notifications", "ops.jsonl")
+	logger := log.NewNopLogger()
+
+	c := newTestChecker()
+	c.StateDir = dir
+	c.PropertiesFilePath = filepath.Join(dir, "services.yaml")
+
+	n := notifier.New(&notifier.Config{Name: "ops", Type: notifier.TYPE_FILE, Path: path}, nil, nil, &logger)
+	c.notifiers = map[string]notifier.Notifier{"ops": n}
+
+	return c, n, path
+}
+
+func TestOutboxSpoolAndRetry(t *testing.T) {
+	c, n, path := newOutboxChecker(t)
+	down := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	c.transition(runResult(STATE_RESTART_FAILED, 0, "start failed"), down)
+
+	for _, subject := range []string{"first", "second"} {
+		if c.notify(&notifier.Message{Service: "alert-test", Subject: subject}, []notifier.Notifier{n}) {
+			t.Fatalf("notify() %q returns delivered", subject)
+		}
+	}
+
+	// error which isn't error of delivery isn't fixed by retry
+	c.notify(&notifier.Message{Service: "alert-test", Subject: "broken"},
+		[]notifier.Notifier{&testNotifier{name: "ops", err: errors.New("can't render template")}})
+
+	if entries := outboxEntries(t, c); len(entries) != 2 {
+		t.Fatalf("outbox has %d notifications, want 2", len(entries))
+	}
+
+	// notifier still fails, so the second notification isn't retried in this run
+	c.retryOutbox(down.Add(time.Minute))
+
+	entries := outboxEntries(t, c)
+
+	if len(entries) != 2 || entries[0].Attempts != 2 || entries[1].Attempts != 1 {
+		t.Fatalf("outbox after failed retry = %v, want attempts 2 and 1", entries)
+	}
+
+	if entries[0].Message.Subject != "first" || len(entries[0].LastError) == 0 {
+		t.Errorf("first outbox notification = %v, want 'first' with last error", entries[0])
+	}
+
+	if err := os.Mkdir(filepath.Dir(path), 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	retried := down.Add(2 * time.Minute)
+	c.retryOutbox(retried)
+
+	if entries := outboxEntries(t, c); len(entries) != 0 {
+		t.Errorf("outbox after successful retry has %d notifications, want 0", len(entries))
+	}
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 ||
+		!strings.Contains(lines[0], `"first"`) || !strings.Contains(lines[1], `"second"`) {
+		t.Errorf("delivered notifications = %q, want 'first' and 'second' in order", lines)
+	}
+
+	if state := c.state.Services["alert-test"]; !state.LastNotified.Equal(retried) {
+		t.Errorf("last notified = %s, want time of retry %s", state.LastNotified, retried)
+	}
+}
+
+func TestOutboxMaxAge(t *testing.T) {
+	c, n, _ := newOutboxChecker(t)
+
+	c.notify(&notifier.Message{Service: "alert-test", Subject: "expired"}, []notifier.Notifier{n})
+	c.retryOutbox(time.Now().Add(OUTBOX_DEFAULT_MAX_AGE + time.Hour))
+
+	if entries := outboxEntries(t, c); len(entries) != 0 {
+		t.Errorf("outbox after 'outbox_max_age' has %d notifications, want 0", len(entries))
+	}
+
+	if len(c.checkerErrorArray) != 1 || !strings.Contains((*c.checkerErrorArray[0]).Error(), "is dropped") {
+		t.Errorf("nanny errors = %d, want error of dropped notification", len(c.checkerErrorArray))
+	}
+}
+
+func TestOutboxUnknownNotifier(t *testing.T) {
+	c, n, _ := newOutboxChecker(t)
+
+	c.notify(&notifier.Message{Service: "alert-test", Subject: "removed"}, []notifier.Notifier{n})
+
+	// notifier was removed from config
+	c.notifiers = map[string]notifier.Notifier{}
+	c.retryOutbox(time.Now())
+
+	if entries := outboxEntries(t, c); len(entries) != 0 {
+		t.Errorf("outbox has %d notifications of unknown notifier, want 0", len(entries))
+	}
+}
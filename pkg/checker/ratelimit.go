@@ -0,0 +1,201 @@
+package checker
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+
+	"github.com/ashokhin/autosys-nanny/pkg/notifier"
+)
+
+// numbers in messages of errors, e.g. pids and exit codes, which don't change kind of error
+var numbersRegexp = regexp.MustCompile(`[0-9]+`)
+
+// errorKind returns name of checker's error type, e.g. 'ErrStartFailed'.
+// Other errors are created with errors.New or fmt.Errorf, so their kind is message with numbers replaced by 'N'.
+func errorKind(err error) string {
+	t := reflect.TypeOf(err)
+
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.PkgPath() == reflect.TypeOf(ErrNoProcName{}).PkgPath() {
+		return t.Name()
+	}
+
+	return numbersRegexp.ReplaceAllString(err.Error(), "N")
+}
+
+// dedupKey returns key of notification: service, state and kinds of errors
+func dedupKey(msg *notifier.Message) string {
+	var kinds []string
+
+	for _, e := range msg.Errors {
+		kinds = append(kinds, errorKind(*e))
+	}
+
+	slices.Sort(kinds)
+
+	return fmt.Sprintf("%s|%s|%s", msg.Service, msg.State, strings.Join(slices.Compact(kinds), ","))
+}
+
+// serviceWindow returns current rate limit window of service
+func (c *Checker) serviceWindow(service string, now time.Time) *rateWindow {
+	rate, ok := c.state.Rates[service]
+
+	if !ok {
+		rate = &rateWindow{Start: now}
+		c.state.Rates[service] = rate
+	}
+
+	return rate
+}
+
+// globalWindow returns current rate limit window of all notifications
+func (c *Checker) globalWindow(now time.Time) *rateWindow {
+	if c.state.Global == nil || now.Sub(c.state.Global.Start) >= c.Config.rateWindow() {
+		// suppressed notifications are summarized by services' windows
+		c.state.Global = &rateWindow{Start: now}
+	}
+
+	return c.state.Global
+}
+
+// allowNotification checks notification by dedup key and rate limits.
+// Suppressed notifications are counted for summary. Notifications about recovery are never suppressed.
+func (c *Checker) allowNotification(msg *notifier.Message, now time.Time) bool {
+	alerting := c.Config.alerting()
+
+	if msg.State == STATE_RECOVERED {
+		return true
+	}
+
+	key := dedupKey(msg)
+
+	if alerting.DedupWindow > 0 {
+		if alert, ok := c.state.Alerts[key]; ok {
+			alert.Suppressed++
+
+			level.Info(*c.logger).Log("msg", "duplicate notification suppressed", "service", msg.Service,
+				"value", key, "suppressed", alert.Suppressed)
+
+			return false
+		}
+	}
+
+	if alerting.ServiceLimit > 0 || alerting.GlobalLimit > 0 {
+		rate := c.serviceWindow(msg.Service, now)
+		global := c.globalWindow(now)
+
+		if alerting.ServiceLimit > 0 && rate.Sent >= alerting.ServiceLimit {
+			rate.Suppressed++
+
+			level.Info(*c.logger).Log("msg", "notification suppressed by service rate limit", "service", msg.Service,
+				"value", alerting.ServiceLimit, "suppressed", rate.Suppressed)
+
+			return false
+		}
+
+		if alerting.GlobalLimit > 0 && global.Sent >= alerting.GlobalLimit {
+			rate.Suppressed++
+			global.Suppressed++
+
+			level.Info(*c.logger).Log("msg", "notification suppressed by global rate limit", "service", msg.Service,
+				"value", alerting.GlobalLimit, "suppressed", global.Suppressed)
+
+			return false
+		}
+
+		rate.Sent++
+		global.Sent++
+	}
+
+	if alerting.DedupWindow > 0 {
+		c.state.Alerts[key] = &alertState{Service: msg.Service, LastSent: now}
+	}
+
+	return true
+}
+
+// suppressedSummary removes expired dedup keys and rate limit window of service.
+// Returns number of notifications which were suppressed within them and start of suppression.
+func (c *Checker) suppressedSummary(service string, now time.Time) (int, time.Time) {
+	var since time.Time
+
+	suppressed := 0
+
+	for key, alert := range c.state.Alerts {
+		if alert.Service != service || now.Sub(alert.LastSent) < c.Config.alerting().DedupWindow {
+			continue
+		}
+
+		if alert.Suppressed > 0 {
+			suppressed += alert.Suppressed
+
+			if since.IsZero() || alert.LastSent.Before(since) {
+				since = alert.LastSent
+			}
+		}
+
+		delete(c.state.Alerts, key)
+	}
+
+	if rate, ok := c.state.Rates[service]; ok && now.Sub(rate.Start) >= c.Config.rateWindow() {
+		if rate.Suppressed > 0 {
+			suppressed += rate.Suppressed
+
+			if since.IsZero() || rate.Start.Before(since) {
+				since = rate.Start
+			}
+		}
+
+		delete(c.state.Rates, service)
+	}
+
+	return suppressed, since
+}
+
+// rateLimitedMessage returns notification of service if it passes deduplication and rate limits.
+// Summary of notifications suppressed within ended windows is added to notification
+// or is returned as separate notification. Returns nil if there is nothing to send.
+func (c *Checker) rateLimitedMessage(s *Service, state string, since time.Time, now time.Time) *notifier.Message {
+	var msg *notifier.Message
+
+	suppressed, suppressedSince := c.suppressedSummary(s.ProcessName, now)
+
+	if len(state) > 0 {
+		msg = c.serviceMessage(s, state, since, now)
+
+		if !c.allowNotification(msg, now) {
+			msg = nil
+		}
+	}
+
+	if suppressed == 0 {
+		return msg
+	}
+
+	summary := fmt.Errorf("%d notifications of service '%s' were suppressed by deduplication and rate limits since %s",
+		suppressed, s.ProcessName, suppressedSince.Format(time.RFC3339))
+
+	if msg != nil {
+		msg.Errors = append([]*error{&summary}, msg.Errors...)
+
+		return msg
+	}
+
+	return &notifier.Message{
+		Hostname: c.hostname,
+		Service:  s.ProcessName,
+		State:    STATE_SUPPRESSED,
+		Subject:  fmt.Sprintf("%s | '%s' suppressed %d alerts", c.subjectPrefix, s.ProcessName, suppressed),
+		Errors:   []*error{&summary},
+		Time:     now,
+	}
+}
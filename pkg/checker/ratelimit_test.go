@@ -0,0 +1,114 @@
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErrorKind(t *testing.T) {
+	startErr := &ErrStartFailed{"app", time.Second, "exit status 1", []string{"line 42"}}
+	otherStartErr := &ErrStartFailed{"app", time.Second, "exit status 2", nil}
+
+	if errorKind(startErr) != "ErrStartFailed" || errorKind(startErr) != errorKind(otherStartErr) {
+		t.Errorf("errorKind() of ErrStartFailed = %q and %q, want 'ErrStartFailed'", errorKind(startErr), errorKind(otherStartErr))
+	}
+
+	// errors of errors.New and fmt.Errorf have the same types, so they differ by messages
+	stopped := fmt.Errorf("service 'app' with pid %d was stopped", 1234)
+	restartedPid := fmt.Errorf("service 'app' with pid %d was stopped", 5678)
+	other := errors.New("can't read pid file")
+
+	if errorKind(stopped) != errorKind(restartedPid) {
+		t.Errorf("errorKind() depends on pid: %q != %q", errorKind(stopped), errorKind(restartedPid))
+	}
+
+	if errorKind(stopped) == errorKind(other) {
+		t.Errorf("errorKind() of different messages = %q", errorKind(other))
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	c := newTestChecker()
+	c.Config.Alerting.DedupWindow = time.Hour
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	restarted := func(message string) *Service {
+		s := runResult("", 1)
+		err := errors.New(message)
+		s.errorArray = append(s.errorArray, &err)
+
+		return s
+	}
+
+	if msg := c.rateLimitedMessage(restarted("service 'app' with pid 10 was stopped"), STATE_RESTARTED, time.Time{}, now); msg == nil {
+		t.Fatal("first notification is suppressed")
+	}
+
+	if msg := c.rateLimitedMessage(restarted("service 'app' with pid 20 was stopped"), STATE_RESTARTED, time.Time{}, now.Add(time.Minute)); msg != nil {
+		t.Errorf("duplicate notification isn't suppressed: %v", msg)
+	}
+
+	if msg := c.rateLimitedMessage(restarted("can't read pid file"), STATE_RESTARTED, time.Time{}, now.Add(2*time.Minute)); msg == nil {
+		t.Error("notification with other error is suppressed")
+	}
+
+	if msg := c.rateLimitedMessage(runResult("", 0), STATE_RECOVERED, now, now.Add(3*time.Minute)); msg == nil {
+		t.Error("'recovered' notification is suppressed")
+	}
+
+	// the first key expired, so summary of suppressed notification is sent with the next one
+	msg := c.rateLimitedMessage(restarted("service 'app' with pid 30 was stopped"), STATE_RESTARTED, time.Time{}, now.Add(2*time.Hour))
+
+	if msg == nil {
+		t.Fatal("notification after dedup window is suppressed")
+	}
+
+	if summary := (*msg.Errors[0]).Error(); !strings.HasPrefix(summary, "1 notifications of service 'alert-test' were suppressed") {
+		t.Errorf("first error = %q, want summary of suppressed notifications", summary)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	c := newTestChecker()
+	c.Config.Alerting.ServiceLimit = 2
+	c.Config.Alerting.RateWindow = time.Hour
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	var sent int
+
+	for i := 0; i < 5; i++ {
+		if c.rateLimitedMessage(runResult("", 1, "restarted"), STATE_RESTARTED, time.Time{}, now.Add(time.Duration(i)*time.Minute)) != nil {
+			sent++
+		}
+	}
+
+	if sent != 2 {
+		t.Errorf("sent %d notifications, want 'service_rate_limit' 2", sent)
+	}
+
+	// summary is sent alone when window ends and there is nothing else to notify
+	msg := c.rateLimitedMessage(runResult("", 0), "", time.Time{}, now.Add(time.Hour))
+
+	if msg == nil || msg.State != STATE_SUPPRESSED || !strings.Contains(msg.Subject, "suppressed 3 alerts") {
+		t.Fatalf("summary after rate limit window = %v, want 3 suppressed alerts", msg)
+	}
+
+	// global limit is shared by services
+	c.Config.Alerting.ServiceLimit = 0
+	c.Config.Alerting.GlobalLimit = 1
+
+	if c.rateLimitedMessage(runResult("", 1, "restarted"), STATE_RESTARTED, time.Time{}, now.Add(2*time.Hour)) == nil {
+		t.Error("first notification in global window is suppressed")
+	}
+
+	other := newTestService("other")
+	err := errors.New("restarted")
+	other.errorArray = []*error{&err}
+
+	if c.rateLimitedMessage(other, STATE_RESTARTED, time.Time{}, now.Add(2*time.Hour)) != nil {
+		t.Error("notification above 'global_rate_limit' isn't suppressed")
+	}
+}
@@ -1,45 +1,126 @@
 package checker
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/user"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	START_DEFAULT_WAIT      time.Duration = 3 * time.Second
+	STOP_DEFAULT_TIMEOUT    time.Duration = 60 * time.Second
+	RESTART_DEFAULT_TIMEOUT time.Duration = 5 * time.Minute
 )
 
 type Service struct {
-	ProcessName  string   `yaml:"process_name"`
-	Description  string   `yaml:"description"`
-	Disabled     bool     `yaml:"disabled"`
-	StartCmd     string   `yaml:"start_cmd"`
-	CmdArgs      []string `yaml:"cmd_args"`
-	StopCmd      string   `yaml:"stop_cmd"`
-	PythonVEnv   string   `yaml:"python_venv"`
-	WorkingDir   string   `yaml:"working_directory"`
-	PidFile      string   `yaml:"pid_file"`
-	EnvList      []string `yaml:"env_vars"`
-	MailList     []string `yaml:"mailing_list"`
-	forceRestart bool
-	errorArray   []*error
-	process      *Process
-	Logger       *log.Logger
+	ProcessName   string        `yaml:"process_name"`
+	Description   string        `yaml:"description"`
+	Disabled      bool          `yaml:"disabled"`
+	StartCmd      string        `yaml:"start_cmd"`
+	CmdArgs       []string      `yaml:"cmd_args"`
+	StopCmd       string        `yaml:"stop_cmd"`
+	StopTimeout   time.Duration `yaml:"stop_timeout"`
+	Shell         shellOption   `yaml:"shell"`
+	PythonVEnv    string        `yaml:"python_venv"`
+	WorkingDir    string        `yaml:"working_directory"`
+	PidFile       string        `yaml:"pid_file"`
+	EnvList       []string      `yaml:"env_vars"`
+	EnvFiles      stringList    `yaml:"env_file"`
+	ClearEnv      bool          `yaml:"clear_env"`
+	SecretVars    []string      `yaml:"secret_env_vars"`
+	User          string        `yaml:"user"`
+	Group         string        `yaml:"group"`
+	SuppGroups    []string      `yaml:"supplementary_groups"`
+	StdoutLog     string        `yaml:"stdout_log"`
+	StderrLog     string        `yaml:"stderr_log"`
+	LogFile       string        `yaml:"log_file"`
+	LogMaxSize    byteSize      `yaml:"log_max_size"`
+	LogBackups    int           `yaml:"log_max_backups"`
+	LogTailLines  int           `yaml:"log_tail_lines"`
+	StartWait     time.Duration `yaml:"start_wait"`
+	DoubleFork    bool          `yaml:"double_fork"`
+	Rlimits       rlimits       `yaml:"rlimits"`
+	Nice          *int          `yaml:"nice"`
+	IONiceClass   string        `yaml:"ionice_class"`
+	IONiceLevel   *int          `yaml:"ionice_level"`
+	OOMScoreAdj   *int          `yaml:"oom_score_adj"`
+	Cgroup        *CgroupConfig `yaml:"cgroup"`
+	KillMode      killMode      `yaml:"kill_mode"`
+	Instances     int           `yaml:"instances"`
+	KillExcess    bool          `yaml:"kill_excess"`
+	KillDupes     bool          `yaml:"kill_duplicates"`
+	Hooks         *Hooks        `yaml:"hooks"`
+	Diagnostics   *Diagnostics  `yaml:"diagnostics"`
+	DependsOn     []string      `yaml:"depends_on"`
+	RestartLimit  time.Duration `yaml:"restart_timeout"`
+	MailList      []string      `yaml:"mailing_list"`
+	Notifiers     []string      `yaml:"notifiers"`
+	forceRestart  bool
+	dryRun        bool
+	cgroupRoot    string
+	restartReason string
+	stderrOffset  int64
+	failState     string
+	started       int
+	exitStatus    string
+	stopOutput    []string
+	diagOutput    string
+	ctx           context.Context
+	errorArray    []*error
+	process       *Process
+	instances     []*Process
+	Logger        *log.Logger
 }
 
 func (s *Service) String() string {
 	return fmt.Sprintf("%+v", *s)
 }
 
+// validate service properties which can't be validated by their types
+func (s *Service) UnmarshalYAML(value *yaml.Node) error {
+	type rawService Service
+
+	if err := value.Decode((*rawService)(s)); err != nil {
+		return err
+	}
+
+	if err := s.checkLimits(); err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+
+	if s.Cgroup != nil {
+		if err := checkCgroupName(s.cgroupName()); err != nil {
+			return fmt.Errorf("line %d: service '%s': %w. set 'name' of 'cgroup'", value.Line, s.ProcessName, err)
+		}
+	}
+
+	if string(s.KillMode) == KILL_MODE_CGROUP && s.Cgroup == nil {
+		return fmt.Errorf("line %d: kill_mode 'cgroup' requires 'cgroup' property", value.Line)
+	}
+
+	if s.Instances < 0 {
+		return fmt.Errorf("line %d: 'instances' should be positive", value.Line)
+	}
+
+	return nil
+}
+
 type Process struct {
 	Cmd     string
 	Cmdline string
 	Pid     int
 	PPid    int
+	Rss     int64
 	ModTime time.Time
 }
 
@@ -47,6 +128,27 @@ func (p *Process) String() string {
 	return fmt.Sprintf("%+v", *p)
 }
 
+// stringList is YAML sequence of strings which also accepts single string
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = stringList{value.Value}
+
+		return nil
+	}
+
+	var list []string
+
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+
+	*l = list
+
+	return nil
+}
+
 func (s *Service) deletePidFile() {
 	var err error
 
@@ -59,11 +161,11 @@ func (s *Service) deletePidFile() {
 
 	level.Debug(*s.Logger).Log("msg", "search pid file for service", "value", s.ProcessName)
 
-	matches, err := filepath.Glob(fmt.Sprintf("./%s", s.PidFile))
+	matches, err := filepath.Glob(s.resolvePath(s.PidFile))
 
 	if err != nil {
 		level.Warn(*s.Logger).Log("msg", "search pid got error", "service", s.ProcessName,
-			"value", fmt.Sprintf("./%s", s.PidFile))
+			"value", s.resolvePath(s.PidFile))
 		s.errorArray = append(s.errorArray, &err)
 	}
 
@@ -89,10 +191,41 @@ func (s *Service) deletePidFile() {
 	}
 }
 
+// kill service's processes with 'syscall.SIGKILL' signal according to 'kill_mode'
 func (s *Service) kill() error {
+	var errs []string
+
+	// cgroup contains exactly all processes of service
+	if s.killMode() == KILL_MODE_CGROUP {
+		return s.killCgroup()
+	}
+
+	for _, p := range s.instances {
+		if err := s.killProcess(p.Pid); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// kill one service's process with 'syscall.SIGKILL' signal according to 'kill_mode'
+func (s *Service) killProcess(pid int) error {
 	var err error
 
-	p, err := os.FindProcess(s.process.Pid)
+	switch s.killMode() {
+	case KILL_MODE_GROUP:
+		return s.killGroup(pid)
+	case KILL_MODE_TREE, KILL_MODE_CGROUP:
+		// all instances share service's cgroup, so single instance is killed with its process tree
+		return s.killTree(pid)
+	}
+
+	p, err := os.FindProcess(pid)
 
 	if err != nil {
 		s.deletePidFile()
@@ -114,6 +247,14 @@ func (s *Service) stop() error {
 	if s.process == nil {
 		s.deletePidFile()
 
+		// main process is absent, so processes left in service's cgroup are orphans
+		if s.Cgroup != nil {
+			if err := s.killCgroup(); err != nil {
+				level.Warn(*s.Logger).Log("msg", "got error when try to kill processes left in cgroup",
+					"service", s.ProcessName, "error", err.Error())
+			}
+		}
+
 		if s.Disabled {
 			level.Debug(*s.Logger).Log("msg", "service disabled and has already stopped", "value", s.ProcessName)
 
@@ -123,32 +264,141 @@ func (s *Service) stop() error {
 		return &ErrZeroPid{s.ProcessName}
 	}
 
+	pid := s.process.Pid
+
+	// failed 'pre_stop' hook doesn't prevent restart of service
+	s.runHook(HOOK_PRE_STOP, pid)
+
 	if len(s.StopCmd) > 0 {
 		// if stop command present than exec stop command
-		level.Debug(*s.Logger).Log("msg", "execute stop command for service",
-			"service", s.ProcessName, "value", s.StopCmd)
+		if stopErr := s.runStopCmd(); stopErr != nil {
+			level.Error(*s.Logger).Log("msg", "got error when try to execute stop command",
+				"service", s.ProcessName, "error", stopErr.Error())
 
-		cmd := exec.Command("bash", "-c", s.StopCmd)
+			s.errorArray = append(s.errorArray, &stopErr)
 
-		level.Debug(*s.Logger).Log("msg", "stop command", "value", cmd.String())
+			// processes of service can be still alive, so fall back to kill
+			level.Warn(*s.Logger).Log("msg", "stop command failed. execute kill process for service",
+				"service", s.ProcessName, "value", "syscall.sigkill", "kill_mode", s.killMode())
 
-		if err := cmd.Run(); err != nil {
-			level.Error(*s.Logger).Log("msg", "got error when try to execute stop command",
-				"value", cmd.String(), "error", err.Error())
+			err = s.kill()
 		}
 	} else {
-		// else kill process with 'syscall.SIGKILL' signal
+		// else kill processes with 'syscall.SIGKILL' signal
 		level.Debug(*s.Logger).Log("msg", "service doesn't have 'stop_cmd'. execute kill process for service",
-			"service", s.ProcessName, "value", "syscall.sigkill")
+			"service", s.ProcessName, "value", "syscall.sigkill", "kill_mode", s.killMode())
 
 		err = s.kill()
 	}
 
 	s.deletePidFile()
 
+	// all instances are replaced by new ones on start
+	s.instances = nil
+
+	if err == nil {
+		s.runHook(HOOK_POST_STOP, pid)
+	}
+
 	return err
 }
 
+func (s *Service) stopTimeout() time.Duration {
+	if s.StopTimeout > 0 {
+		return s.StopTimeout
+	}
+
+	return STOP_DEFAULT_TIMEOUT
+}
+
+// runStopCmd executes 'stop_cmd' with 'stop_timeout'.
+// Output of failed command is returned in error.
+func (s *Service) runStopCmd() error {
+	level.Debug(*s.Logger).Log("msg", "execute stop command for service",
+		"service", s.ProcessName, "value", s.StopCmd, "timeout", s.stopTimeout())
+
+	cmd, _, err := s.newCommand(s.StopCmd, nil)
+
+	if err != nil {
+		return &ErrStopCmd{s.ProcessName, s.StopCmd, err.Error(), nil}
+	}
+
+	level.Debug(*s.Logger).Log("msg", "stop command", "value", cmd.String())
+
+	output, err := s.runCommand(cmd, s.stopTimeout())
+
+	// output is added to notification
+	s.stopOutput = tailLines(output, s.logTailLines())
+
+	if err != nil {
+		return &ErrStopCmd{s.ProcessName, s.commandLine(cmd), err.Error(), s.stopOutput}
+	}
+
+	level.Debug(*s.Logger).Log("msg", "stop command succeeded", "service", s.ProcessName,
+		"value", strings.TrimSpace(output))
+
+	return nil
+}
+
+// environment for service's commands:
+// nanny's environment (or minimal one with 'clear_env') + account of 'user' + 'env_file' + 'env_vars'
+func (s *Service) environ(account *user.User) (*environ, error) {
+	var env *environ
+
+	if s.ClearEnv {
+		env = minimalEnviron()
+	} else {
+		env = newEnviron(os.Environ())
+	}
+
+	if account != nil {
+		env.Set("HOME", account.HomeDir)
+		env.Set("USER", account.Username)
+		env.Set("LOGNAME", account.Username)
+	}
+
+	for _, f := range s.EnvFiles {
+		level.Debug(*s.Logger).Log("msg", "load environment file", "service", s.ProcessName, "value", f)
+
+		if err := env.loadFile(s.resolvePath(f)); err != nil {
+			return nil, &ErrBadEnv{s.ProcessName, fmt.Sprintf("can't load 'env_file' '%s': %s", f, err.Error())}
+		}
+	}
+
+	if err := env.loadList(s.EnvList); err != nil {
+		return nil, &ErrBadEnv{s.ProcessName, fmt.Sprintf("can't interpolate 'env_vars': %s", err.Error())}
+	}
+
+	for _, key := range s.SecretVars {
+		env.MarkSecret(key)
+	}
+
+	return env, nil
+}
+
+// start command with python virtual environment
+func (s *Service) startCmdLine() string {
+	startCmd := s.StartCmd
+
+	if len(s.PythonVEnv) > 0 {
+		if strings.HasPrefix(startCmd, "python") {
+
+			startCmd = fmt.Sprintf("%s/bin/%s", s.PythonVEnv, startCmd)
+			level.Debug(*s.Logger).Log("msg", "add 'python_venv' to 'start_cmd'",
+				"python_venv", s.PythonVEnv, "value", startCmd)
+		} else {
+			level.Warn(*s.Logger).Log("msg", "error add 'python_venv' to 'start_cmd'",
+				"error", "for using python virtual environment 'start_cmd' should be started from 'python' word")
+		}
+	}
+
+	return startCmd
+}
+
+func (s *Service) startCommand() (*exec.Cmd, *environ, error) {
+	return s.newCommand(s.startCmdLine(), s.CmdArgs)
+}
+
 func (s *Service) start() error {
 	var err error
 
@@ -164,80 +414,344 @@ func (s *Service) start() error {
 		level.Debug(*s.Logger).Log("msg", "service doesn't have start command in 'start_cmd' property",
 			"value", s.ProcessName)
 
+		s.failState = STATE_DOWN
+
 		return &ErrNoStartCmd{s.ProcessName}
 	}
 
-	if len(s.PythonVEnv) > 0 {
-		if strings.HasPrefix(s.StartCmd, "python") {
+	// failed 'pre_start' hook cancels start, so service isn't treated as started by caller
+	if err := s.runHook(HOOK_PRE_START, 0); err != nil {
+		s.failState = STATE_RESTART_FAILED
+		s.runHook(HOOK_ON_FAILURE, 0)
 
-			s.StartCmd = fmt.Sprintf("%s/bin/%s", s.PythonVEnv, s.StartCmd)
-			level.Debug(*s.Logger).Log("msg", "add 'python_venv' to 'start_cmd'",
-				"python_venv", s.PythonVEnv, "value", s.StartCmd)
-		} else {
-			level.Warn(*s.Logger).Log("msg", "error add 'python_venv' to 'start_cmd'",
-				"error", "for using python virtual environment 'start_cmd' should be started from 'python' word")
+		return err
+	}
+
+	var cmdLine string
+
+	started := 0
+	running := len(s.instances)
+	missing := s.missingInstances()
+
+	for i := 0; i < missing; i++ {
+		if line, pid := s.startInstance(); pid > 0 {
+			cmdLine = line
+			started++
+
+			s.runHook(HOOK_POST_START, pid)
 		}
 	}
 
-	// version with bash
-	if len(s.CmdArgs) > 0 {
-		s.StartCmd = fmt.Sprintf("%s %s", s.StartCmd, strings.Join(s.CmdArgs, " "))
+	s.started = started
 
-		level.Debug(*s.Logger).Log("msg", "add 'cmd_args' to 'start_cmd'", "cmd_args", fmt.Sprintf("%v", s.CmdArgs), "value", s.StartCmd)
+	if started < missing {
+		s.failState = STATE_RESTART_FAILED
+		s.runHook(HOOK_ON_FAILURE, 0)
 	}
 
-	cmd := exec.Command("bash", "-c", s.StartCmd)
+	if started > 0 {
+		stopped := "was stopped"
+
+		if running > 0 {
+			stopped = fmt.Sprintf("had %d of %d instances running", running, s.instancesCount())
+		}
+
+		if len(s.restartReason) > 0 {
+			stopped = fmt.Sprintf("%s (%s)", stopped, s.restartReason)
+		}
+
+		err1 := fmt.Errorf("service '%s' %s and now started. Start command: '%s'", s.ProcessName, stopped, cmdLine)
+
+		if s.instancesCount() > 1 {
+			err1 = fmt.Errorf("service '%s' %s and now %d instances started. Start command: '%s'",
+				s.ProcessName, stopped, started, cmdLine)
+		}
 
-	// version without bash
-	//cmd := exec.Command(s.StartCmd, s.CmdArgs...)
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, s.EnvList...)
+		s.errorArray = append(s.errorArray, &err1)
+	}
+
+	// error of start is already in s.errorArray
+	err = nil
+
+	if s.forceRestart {
+		err = &ErrSvcRestartedForce{s.ProcessName}
+	}
+
+	return err
+}
+
+// startInstance starts one service's process.
+// Returns human-readable start command and pid of process if it's still alive after 'start_wait'.
+// Errors are added into s.errorArray.
+func (s *Service) startInstance() (string, int) {
+	cmd, env, err := s.startCommand()
+
+	if err != nil {
+		level.Error(*s.Logger).Log("msg", "got error when try to prepare start command",
+			"service", s.ProcessName, "error", err.Error())
+
+		s.errorArray = append(s.errorArray, &err)
+
+		return "", 0
+	}
 
 	level.Debug(*s.Logger).Log("msg", "execute start command",
 		"service", s.ProcessName, "value", fmt.Sprintf("%+v", cmd.String()))
 	level.Debug(*s.Logger).Log("msg", "environment variables",
-		"service", s.ProcessName, "value", fmt.Sprintf("%s", cmd.Env))
+		"service", s.ProcessName, "value", fmt.Sprintf("%s", env.MaskedList()))
 
-	if err := cmd.Start(); err != nil {
+	logFiles, err := s.openLogs(cmd)
+
+	if err != nil {
+		level.Error(*s.Logger).Log("msg", "got error when try to open log files",
+			"service", s.ProcessName, "error", err.Error())
+
+		s.errorArray = append(s.errorArray, &err)
+
+		return "", 0
+	}
+
+	// child process has own copies of log file descriptors
+	defer closeFiles(logFiles)
+
+	var cgroupDir *os.File
+
+	if s.Cgroup != nil {
+		if cgroupDir, err = s.setupCgroup(); err != nil {
+			level.Error(*s.Logger).Log("msg", "got error when try to setup cgroup",
+				"service", s.ProcessName, "value", s.cgroupName(), "error", err.Error())
+
+			var err error = &ErrCgroup{s.ProcessName, err.Error()}
+			s.errorArray = append(s.errorArray, &err)
+
+			return "", 0
+		}
+
+		defer cgroupDir.Close()
+	}
+
+	cmdLine := s.commandLine(cmd)
+	limitErrors, err := s.withLimits(cmd)
+
+	if err != nil {
+		level.Error(*s.Logger).Log("msg", "got error when try to prepare limits",
+			"service", s.ProcessName, "error", err.Error())
+
+		s.errorArray = append(s.errorArray, &err)
+
+		return "", 0
+	}
+
+	pid, chExit, err := s.launch(cmd, cgroupDir)
+
+	// child process has own copy of limits errors pipe
+	closeFiles(cmd.ExtraFiles)
+
+	if err != nil {
 		level.Error(*s.Logger).Log("msg", "got error when try to start command",
-			"service", s.ProcessName, "value", cmd.String(), "error", err.Error())
+			"service", s.ProcessName, "value", cmdLine, "error", err.Error())
 
 		s.errorArray = append(s.errorArray, &err)
-	} else {
-		err1 := fmt.Errorf("service '%s' was stopped and now started. Start command: '%s'", s.ProcessName, s.StartCmd)
-		s.errorArray = append(s.errorArray, &err1)
+
+		if limitErrors != nil {
+			limitErrors.Close()
+		}
+
+		return "", 0
 	}
 
-	if err == nil && s.forceRestart {
-		err = &ErrSvcRestartedForce{s.ProcessName}
+	level.Debug(*s.Logger).Log("msg", "service started", "service", s.ProcessName, "value", pid)
+
+	if limitErrors != nil {
+		for _, limitErr := range readLimitErrors(limitErrors) {
+			var err error = &ErrLimits{s.ProcessName, limitErr}
+
+			level.Error(*s.Logger).Log("msg", "got error when try to apply limits",
+				"service", s.ProcessName, "value", pid, "error", err.Error())
+
+			s.errorArray = append(s.errorArray, &err)
+		}
 	}
 
-	return err
+	if err := s.waitStarted(pid, chExit); err != nil {
+		level.Error(*s.Logger).Log("msg", "service start wasn't confirmed",
+			"service", s.ProcessName, "value", cmdLine, "error", err.Error())
+
+		s.errorArray = append(s.errorArray, &err)
+
+		return "", 0
+	}
+
+	return cmdLine, pid
 }
 
-func (s *Service) RestartProcess(forceRestart bool) error {
-	var err error
+// waitStarted waits 'start_wait' period and returns error if started process has exited during it
+// or if 'restart_timeout' has expired before start was confirmed
+func (s *Service) waitStarted(pid int, chExit <-chan error) error {
+	select {
+	case err := <-chExit:
+		return s.startFailed(err)
+	case <-time.After(s.startWait()):
+		// process may exit at the end of 'start_wait' before its exit is received
+		if !processAlive(pid) {
+			return s.startFailed(<-chExit)
+		}
 
-	s.forceRestart = forceRestart
-	cwd, _ := os.Getwd()
+		return nil
+	case <-s.context().Done():
+		s.failState = STATE_RESTART_FAILED
 
-	level.Debug(*s.Logger).Log("msg", "restart service", "value", s.ProcessName)
+		return &ErrStartTimeout{s.ProcessName, s.restartTimeout()}
+	}
+}
 
-	if s.WorkingDir != "" {
-		defer os.Chdir(cwd)
-		level.Debug(*s.Logger).Log("msg", "change current working directory",
-			"service", s.ProcessName, "value", s.WorkingDir)
+// startFailed returns error of start of process exited during 'start_wait'
+func (s *Service) startFailed(err error) error {
+	exitStatus := "exit status 0"
 
-		if err := os.Chdir(s.WorkingDir); err != nil {
-			level.Error(*s.Logger).Log("msg", "got error when try to change working directory",
-				"service", s.ProcessName, "value", s.WorkingDir, "error", err.Error())
+	if err != nil {
+		exitStatus = err.Error()
+	}
 
-			s.errorArray = append(s.errorArray, &err)
+	s.failState = STATE_RESTART_FAILED
+	s.exitStatus = exitStatus
+
+	return &ErrStartFailed{s.ProcessName, s.startWait(), exitStatus, s.startStderrTail()}
+}
+
+// log what restart would do without stopping or starting anything
+func (s *Service) dryRunRestart() error {
+	if s.process != nil {
+		if len(s.StopCmd) > 0 {
+			level.Info(*s.Logger).Log("msg", "dry run. service would be stopped by 'stop_cmd'",
+				"service", s.ProcessName, "value", s.StopCmd)
+		} else {
+			level.Info(*s.Logger).Log("msg", "dry run. service process would be killed",
+				"service", s.ProcessName, "value", s.process.Pid, "kill_mode", s.killMode())
+		}
+
+		s.dryRunHooks(HOOK_PRE_STOP, HOOK_POST_STOP)
+	}
+
+	if s.Diagnostics != nil {
+		level.Info(*s.Logger).Log("msg", "dry run. diagnostic command would be executed",
+			"service", s.ProcessName, "value", s.Diagnostics.Cmd, "timeout", s.Diagnostics.timeout())
+	}
+
+	if s.Disabled {
+		return nil
+	}
+
+	if len(s.StartCmd) == 0 {
+		return &ErrNoStartCmd{s.ProcessName}
+	}
+
+	cmd, env, err := s.startCommand()
+
+	if err != nil {
+		return err
+	}
+
+	level.Info(*s.Logger).Log("msg", "dry run. service would be started",
+		"service", s.ProcessName, "value", cmd.String(), "working_directory", s.WorkingDir,
+		"shell", s.Shell.String(), "user", s.User, "group", s.Group,
+		"env", fmt.Sprintf("%s", env.MaskedList()))
+
+	s.dryRunHooks(HOOK_PRE_START, HOOK_POST_START)
+
+	return nil
+}
+
+func (s *Service) startWait() time.Duration {
+	if s.StartWait > 0 {
+		return s.StartWait
+	}
+
+	return START_DEFAULT_WAIT
+}
+
+func (s *Service) restartTimeout() time.Duration {
+	if s.RestartLimit > 0 {
+		return s.RestartLimit
+	}
+
+	return RESTART_DEFAULT_TIMEOUT
+}
+
+// context of current restart with 'restart_timeout' deadline
+func (s *Service) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+
+	return s.ctx
+}
+
+// checkWorkingDir returns error if 'working_directory' doesn't exist or isn't a directory
+func (s *Service) checkWorkingDir() error {
+	if s.WorkingDir == "" {
+		return nil
+	}
+
+	fi, err := os.Stat(s.WorkingDir)
+
+	if err == nil && !fi.IsDir() {
+		err = fmt.Errorf("'working_directory' '%s' isn't a directory", s.WorkingDir)
+	}
+
+	if err != nil {
+		level.Error(*s.Logger).Log("msg", "got error when try to check working directory",
+			"service", s.ProcessName, "value", s.WorkingDir, "error", err.Error())
+
+		s.errorArray = append(s.errorArray, &err)
+
+		return err
+	}
+
+	return nil
+}
+
+// resolvePath returns path relative to 'working_directory' for relative paths of service's files
+func (s *Service) resolvePath(path string) string {
+	if len(path) == 0 || filepath.IsAbs(path) || s.WorkingDir == "" {
+		return path
+	}
+
+	return filepath.Join(s.WorkingDir, path)
+}
+
+func (s *Service) RestartProcess(forceRestart bool, dryRun bool) error {
+	var err error
+
+	s.forceRestart = forceRestart
+	s.dryRun = dryRun
+
+	if s.dryRun {
+		if err := s.dryRunRestart(); err != nil {
+			level.Error(*s.Logger).Log("msg", "dry run. service can't be restarted",
+				"value", s.ProcessName, "error", err.Error())
 
 			return err
 		}
+
+		return nil
+	}
+
+	level.Debug(*s.Logger).Log("msg", "restart service", "value", s.ProcessName)
+
+	if s.process == nil {
+		s.restartReason = s.oomRestartReason()
+	}
+
+	if err := s.checkWorkingDir(); err != nil {
+		s.failState = STATE_RESTART_FAILED
+
+		return err
 	}
 
+	// diagnostics are collected while failed service's processes are still there
+	s.runDiagnostics()
+
 	if err := s.stop(); err != nil {
 		var errZeroPid *ErrZeroPid
 
@@ -251,12 +765,21 @@ func (s *Service) RestartProcess(forceRestart bool) error {
 				"value", s.ProcessName, "error", err.Error())
 
 			s.errorArray = append(s.errorArray, &err)
+			s.failState = STATE_RESTART_FAILED
+			s.runHook(HOOK_ON_FAILURE, s.process.Pid)
+
 			return err
 		}
 	}
 
 	if err := s.start(); err != nil {
 		var errSrvRestartedForce *ErrSvcRestartedForce
+		var errHook *ErrHook
+
+		if errors.As(err, &errHook) {
+			// error of 'pre_start' hook is already in s.errorArray
+			return err
+		}
 
 		if errors.As(err, &errSrvRestartedForce) {
 			level.Warn(*s.Logger).Log("msg", "got warning when try to start service",
@@ -0,0 +1,233 @@
+package checker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+const (
+	// first argument of nanny executed as intermediate process for 'double_fork'
+	SPAWN_EXEC_ARG string = "__spawn-detached"
+	// exit status of intermediate process which couldn't start command
+	SPAWN_EXEC_FAILURE int = 127
+	// interval of checking that process started with 'double_fork' is still alive
+	SPAWN_POLL_INTERVAL time.Duration = 100 * time.Millisecond
+)
+
+// ErrExitUnknown is exit status of process which isn't nanny's child
+var ErrExitUnknown = errors.New("unknown exit status")
+
+// launch starts service's command in a new session, so signals sent to nanny's process group
+// (e.g. SIGINT to AutoSys job) don't reach service.
+// With cgroupDir process is created directly inside service's cgroup, so none of its children can escape it.
+// Returns pid of service's process and channel which receives process exit status.
+// Exit status is collected by waiting for the child, so nanny never leaves zombies.
+func (s *Service) launch(cmd *exec.Cmd, cgroupDir *os.File) (int, <-chan error, error) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	cmd.SysProcAttr.Setsid = true
+
+	if cgroupDir == nil {
+		return s.launchCmd(cmd)
+	}
+
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(cgroupDir.Fd())
+
+	pid, chExit, err := s.launchCmd(cmd)
+
+	// clone3(2) with CLONE_INTO_CGROUP is available since linux 5.7,
+	// on older kernels process is moved into cgroup right after launch
+	if errors.Is(err, syscall.ENOSYS) {
+		level.Debug(*s.Logger).Log("msg", "clone into cgroup unsupported. move process after launch",
+			"service", s.ProcessName)
+
+		sysProcAttr := *cmd.SysProcAttr
+		sysProcAttr.UseCgroupFD = false
+
+		cmd = &exec.Cmd{
+			Path:        cmd.Path,
+			Args:        cmd.Args,
+			Env:         cmd.Env,
+			Dir:         cmd.Dir,
+			Stdout:      cmd.Stdout,
+			Stderr:      cmd.Stderr,
+			ExtraFiles:  cmd.ExtraFiles,
+			SysProcAttr: &sysProcAttr,
+		}
+
+		if pid, chExit, err = s.launchCmd(cmd); err != nil {
+			return 0, nil, err
+		}
+
+		if err := s.moveToCgroup(pid); err != nil {
+			var err error = &ErrCgroup{s.ProcessName, err.Error()}
+
+			level.Error(*s.Logger).Log("msg", "got error when try to move process to cgroup",
+				"service", s.ProcessName, "value", pid, "error", err.Error())
+
+			s.errorArray = append(s.errorArray, &err)
+		}
+	}
+
+	return pid, chExit, err
+}
+
+func (s *Service) launchCmd(cmd *exec.Cmd) (int, <-chan error, error) {
+	if s.DoubleFork {
+		return s.launchDetached(cmd)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return 0, nil, err
+	}
+
+	chExit := make(chan error, 1)
+
+	go func() {
+		chExit <- cmd.Wait()
+	}()
+
+	return cmd.Process.Pid, chExit, nil
+}
+
+// launchDetached starts command through intermediate nanny process which exits right after start,
+// so service process is reparented to init and isn't nanny's child at all
+func (s *Service) launchDetached(cmd *exec.Cmd) (int, <-chan error, error) {
+	self, err := os.Executable()
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	pidReader, pidWriter, err := os.Pipe()
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	defer pidReader.Close()
+
+	// descriptors of command keep their numbers, pid pipe is the next one
+	pidFd := 3 + len(cmd.ExtraFiles)
+
+	spawner := &exec.Cmd{
+		Path:        self,
+		Args:        append([]string{self, SPAWN_EXEC_ARG, strconv.Itoa(pidFd), cmd.Path}, cmd.Args...),
+		Env:         cmd.Env,
+		Dir:         cmd.Dir,
+		Stdout:      cmd.Stdout,
+		Stderr:      cmd.Stderr,
+		ExtraFiles:  append(slices.Clip(cmd.ExtraFiles), pidWriter),
+		SysProcAttr: cmd.SysProcAttr,
+	}
+
+	level.Debug(*s.Logger).Log("msg", "start command through intermediate process",
+		"service", s.ProcessName, "value", spawner.String())
+
+	err = spawner.Start()
+	pidWriter.Close()
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	pidLine, readErr := bufio.NewReader(pidReader).ReadString('\n')
+
+	if err := spawner.Wait(); err != nil {
+		return 0, nil, fmt.Errorf("intermediate process failed: %w", err)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(pidLine))
+
+	if err != nil {
+		return 0, nil, fmt.Errorf("can't read pid from intermediate process: %v %v", readErr, err)
+	}
+
+	chExit := make(chan error, 1)
+
+	// process isn't nanny's child, so the only way to know it has exited is polling
+	go func() {
+		for processAlive(pid) {
+			time.Sleep(SPAWN_POLL_INTERVAL)
+		}
+
+		chExit <- ErrExitUnknown
+	}()
+
+	return pid, chExit, nil
+}
+
+// SpawnDetached is entry point of intermediate process for 'double_fork'.
+// args are number of descriptor for pid of started process, path to executable and its argv.
+// Descriptors below pid descriptor are passed to command as is.
+// Unlike background job of POSIX shell, command starts with default SIGINT and SIGQUIT handlers.
+// Never returns: exits after command start or with SPAWN_EXEC_FAILURE.
+func SpawnDetached(args []string) {
+	if len(args) < 3 {
+		spawnFailed(fmt.Errorf("expected pid descriptor, path and arguments, got %q", args))
+	}
+
+	pidFd, err := strconv.Atoi(args[0])
+
+	if err != nil || pidFd < 3 {
+		spawnFailed(fmt.Errorf("bad pid descriptor '%s'", args[0]))
+	}
+
+	syscall.CloseOnExec(pidFd)
+
+	files := make([]*os.File, pidFd)
+
+	for fd := range files {
+		files[fd] = os.NewFile(uintptr(fd), "")
+	}
+
+	// signals ignored by nanny's parent stay ignored after exec, handled ones are reset to default
+	signal.Notify(make(chan os.Signal, 1), syscall.SIGINT, syscall.SIGQUIT)
+
+	process, err := os.StartProcess(args[1], args[2:], &os.ProcAttr{Env: os.Environ(), Files: files})
+
+	if err != nil {
+		spawnFailed(err)
+	}
+
+	pidFile := os.NewFile(uintptr(pidFd), "pid")
+
+	if _, err := fmt.Fprintf(pidFile, "%d\n", process.Pid); err != nil {
+		spawnFailed(err)
+	}
+
+	os.Exit(0)
+}
+
+func spawnFailed(err error) {
+	fmt.Fprintf(os.Stderr, "autosys-nanny: can't start detached command: %s\n", err.Error())
+	os.Exit(SPAWN_EXEC_FAILURE)
+}
+
+// processAlive returns true if process exists and isn't a zombie
+func processAlive(pid int) bool {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+
+	if err != nil {
+		return false
+	}
+
+	// state field follows command name in parentheses which may contain spaces
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+
+	return len(fields) > 0 && fields[0] != "Z"
+}
@@ -0,0 +1,97 @@
+package checker
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// test binary is intermediate process of 'double_fork' and limits helper like nanny itself
+	if len(os.Args) > 1 && os.Args[1] == SPAWN_EXEC_ARG {
+		SpawnDetached(os.Args[2:])
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == LIMITS_EXEC_ARG {
+		ExecWithLimits(os.Args[2:])
+	}
+
+	os.Exit(m.Run())
+}
+
+// procStatus returns value of field from /proc/<pid>/status
+func procStatus(t *testing.T, pid int, field string) string {
+	status, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range strings.Split(string(status), "\n") {
+		if value, ok := strings.CutPrefix(line, field+":"); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+
+	t.Fatalf("no field '%s' in status of process %d", field, pid)
+
+	return ""
+}
+
+func TestLaunchDetached(t *testing.T) {
+	s := newTestService("spawn-test")
+	s.Shell = shellOption{direct: true}
+	s.DoubleFork = true
+
+	cmd, _, err := s.newCommand("sleep", []string{"10"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid, chExit, err := s.launch(cmd, nil)
+
+	if err != nil {
+		t.Fatalf("launch() unexpected error: %v", err)
+	}
+
+	defer syscall.Kill(pid, syscall.SIGKILL)
+
+	if ppid := procStatus(t, pid, "PPid"); ppid == strconv.Itoa(os.Getpid()) {
+		t.Errorf("detached process %d is a child of nanny", pid)
+	}
+
+	sid, _, _ := syscall.RawSyscall(syscall.SYS_GETSID, uintptr(pid), 0, 0)
+	nannySid, _, _ := syscall.RawSyscall(syscall.SYS_GETSID, 0, 0, 0)
+
+	if sid == nannySid {
+		t.Errorf("detached process %d is in nanny's session %d", pid, sid)
+	}
+
+	ignored, err := strconv.ParseUint(procStatus(t, pid, "SigIgn"), 16, 64)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sig := range []syscall.Signal{syscall.SIGINT, syscall.SIGQUIT} {
+		if ignored&(1<<(sig-1)) != 0 {
+			t.Errorf("detached process ignores %s", sig)
+		}
+	}
+
+	syscall.Kill(pid, syscall.SIGKILL)
+
+	select {
+	case err := <-chExit:
+		if err != ErrExitUnknown {
+			t.Errorf("exit status = %v, want %v", err, ErrExitUnknown)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("exit of detached process isn't detected")
+	}
+}
@@ -0,0 +1,196 @@
+package checker
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+const (
+	STATE_DEFAULT_DIR string = "/var/tmp/autosys-nanny"
+	// persisted states of service
+	STATE_UP             string = "up"
+	STATE_DOWN           string = "down"
+	STATE_RESTART_FAILED string = "restart-failed"
+	// transitions which are reported but not persisted
+	STATE_RESTARTED  string = "restarted"
+	STATE_STILL_DOWN string = "still-down"
+	STATE_RECOVERED  string = "recovered"
+	// summary of notifications suppressed by deduplication and rate limits
+	STATE_SUPPRESSED string = "suppressed"
+	// notifications of run grouped by recipients
+	STATE_DIGEST string = "digest"
+)
+
+// ServiceState is status of service persisted between nanny runs
+type ServiceState struct {
+	State string `json:"state"`
+	// time of last change of state
+	Since time.Time `json:"since"`
+	// time of last delivered notification about down service
+	LastNotified time.Time `json:"last_notified"`
+	// number of checks which found service down
+	Failures int `json:"failures,omitempty"`
+	// service was started by previous run and wasn't found running since
+	Restarted bool `json:"restarted,omitempty"`
+	// number of service's starts by nanny
+	Restarts int `json:"restarts,omitempty"`
+}
+
+func (s *ServiceState) String() string {
+	return fmt.Sprintf("%+v", *s)
+}
+
+// alertState is last sent notification with dedup key
+type alertState struct {
+	Service  string    `json:"service"`
+	LastSent time.Time `json:"last_sent"`
+	// number of notifications suppressed since last sent one
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// rateWindow counts notifications within rate limit window
+type rateWindow struct {
+	Start      time.Time `json:"start"`
+	Sent       int       `json:"sent"`
+	Suppressed int       `json:"suppressed,omitempty"`
+}
+
+// stateFile is content of state file
+type stateFile struct {
+	Services map[string]*ServiceState `json:"services"`
+	// dedup keys of notifications
+	Alerts map[string]*alertState `json:"alerts,omitempty"`
+	// rate limit windows of services
+	Rates map[string]*rateWindow `json:"rates,omitempty"`
+	// rate limit window of all notifications
+	Global *rateWindow `json:"global,omitempty"`
+}
+
+func newStateFile() *stateFile {
+	return &stateFile{
+		Services: make(map[string]*ServiceState),
+		Alerts:   make(map[string]*alertState),
+		Rates:    make(map[string]*rateWindow),
+	}
+}
+
+// service is down if it can't be started or its restart failed
+func (s *ServiceState) isDown() bool {
+	return s.State == STATE_DOWN || s.State == STATE_RESTART_FAILED
+}
+
+// stateName returns name of config file with hash of its path,
+// so configs with same names from different directories don't share state
+func (c *Checker) stateName() string {
+	name := strings.TrimSuffix(filepath.Base(c.PropertiesFilePath), filepath.Ext(c.PropertiesFilePath))
+
+	hash := fmt.Sprintf("%x", sha1.Sum([]byte(c.PropertiesFilePath)))
+
+	return fmt.Sprintf("%s-%s", name, hash[:8])
+}
+
+// statePath returns path of state file of config file in Checker.StateDir
+func (c *Checker) statePath() string {
+	return filepath.Join(c.StateDir, c.stateName()+".json")
+}
+
+// loadState reads services' states from state file. Missing state file means all services were up.
+func (c *Checker) loadState() error {
+	c.state = newStateFile()
+
+	level.Debug(*c.logger).Log("msg", "load state file", "value", c.statePath())
+
+	data, err := os.ReadFile(c.statePath())
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, c.state); err != nil {
+		c.state = newStateFile()
+
+		return fmt.Errorf("state file '%s' is corrupted: %w", c.statePath(), err)
+	}
+
+	// state file of previous version without some sections
+	if c.state.Services == nil {
+		c.state.Services = make(map[string]*ServiceState)
+	}
+
+	if c.state.Alerts == nil {
+		c.state.Alerts = make(map[string]*alertState)
+	}
+
+	if c.state.Rates == nil {
+		c.state.Rates = make(map[string]*rateWindow)
+	}
+
+	return nil
+}
+
+// saveState atomically replaces state file by states of services from config
+func (c *Checker) saveState() error {
+	states := newStateFile()
+	states.Global = c.state.Global
+
+	for _, s := range c.Config.Services {
+		if state, ok := c.state.Services[s.ProcessName]; ok {
+			states.Services[s.ProcessName] = state
+		}
+
+		if rate, ok := c.state.Rates[s.ProcessName]; ok {
+			states.Rates[s.ProcessName] = rate
+		}
+	}
+
+	for key, alert := range c.state.Alerts {
+		if _, ok := states.Services[alert.Service]; ok {
+			states.Alerts[key] = alert
+		}
+	}
+
+	data, err := json.MarshalIndent(states, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(c.StateDir, 0750); err != nil {
+		return err
+	}
+
+	level.Debug(*c.logger).Log("msg", "save state file", "value", c.statePath())
+
+	tmp, err := os.CreateTemp(c.StateDir, ".state-*")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.statePath())
+}
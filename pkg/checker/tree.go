@@ -0,0 +1,132 @@
+package checker
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/go-kit/log/level"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	KILL_MODE_PROCESS string = "process"
+	KILL_MODE_GROUP   string = "group"
+	KILL_MODE_TREE    string = "tree"
+	KILL_MODE_CGROUP  string = "cgroup"
+)
+
+var killModes = []string{KILL_MODE_PROCESS, KILL_MODE_GROUP, KILL_MODE_TREE, KILL_MODE_CGROUP}
+
+// children pids of every process from processesList built from processes' PPid
+var processesChildren map[int][]int
+
+// killMode is 'kill_mode' property of service
+type killMode string
+
+func (m *killMode) UnmarshalYAML(value *yaml.Node) error {
+	if !slices.Contains(killModes, value.Value) {
+		return fmt.Errorf("line %d: unsupported kill_mode '%s'. supported values: %s",
+			value.Line, value.Value, strings.Join(killModes, ", "))
+	}
+
+	*m = killMode(value.Value)
+
+	return nil
+}
+
+func buildProcessesTree() {
+	processesChildren = make(map[int][]int)
+
+	for pid, p := range processesList {
+		processesChildren[p.PPid] = append(processesChildren[p.PPid], pid)
+	}
+
+	for ppid := range processesChildren {
+		slices.Sort(processesChildren[ppid])
+	}
+}
+
+// descendants returns pids of all children of process recursively, parents before their children
+func descendants(pid int) []int {
+	var result []int
+
+	queue := slices.Clone(processesChildren[pid])
+
+	for len(queue) > 0 {
+		child := queue[0]
+		queue = queue[1:]
+
+		result = append(result, child)
+		queue = append(queue, processesChildren[child]...)
+	}
+
+	return result
+}
+
+// isDescendant returns true if process is a child of ancestor process on any level
+func isDescendant(pid int, ancestor int) bool {
+	for p, ok := processesList[pid]; ok && p.PPid != 0; p, ok = processesList[p.PPid] {
+		if p.PPid == ancestor {
+			return true
+		}
+	}
+
+	return false
+}
+
+// effective 'kill_mode': 'cgroup' for services in cgroup, 'process' otherwise
+func (s *Service) killMode() string {
+	if len(s.KillMode) > 0 {
+		return string(s.KillMode)
+	}
+
+	if s.Cgroup != nil {
+		return KILL_MODE_CGROUP
+	}
+
+	return KILL_MODE_PROCESS
+}
+
+// killGroup kills process group of service's process
+func (s *Service) killGroup(pid int) error {
+	pgid, err := syscall.Getpgid(pid)
+
+	if err != nil {
+		return err
+	}
+
+	// service started not by nanny may share process group with nanny
+	if pgid == syscall.Getpgrp() {
+		return fmt.Errorf("service '%s' process %d is in nanny's process group %d", s.ProcessName, pid, pgid)
+	}
+
+	level.Debug(*s.Logger).Log("msg", "kill process group", "service", s.ProcessName, "value", pgid)
+
+	return syscall.Kill(-pgid, syscall.SIGKILL)
+}
+
+// killTree kills service's process and all its descendants
+func (s *Service) killTree(pid int) error {
+	var errs []string
+
+	// collect descendants before kill because children are reparented after parent's death
+	pids := append([]int{pid}, descendants(pid)...)
+
+	level.Debug(*s.Logger).Log("msg", "kill process tree", "service", s.ProcessName,
+		"value", fmt.Sprintf("%v", pids))
+
+	// parents are killed first, so they can't fork new children
+	for _, p := range pids {
+		if err := syscall.Kill(p, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+			errs = append(errs, fmt.Sprintf("pid %d: %s", p, err.Error()))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("can't kill processes of service '%s': %s", s.ProcessName, strings.Join(errs, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package checker
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadTestProcesses fills processesList with pids and parent pids of all processes from /proc
+func loadTestProcesses(t *testing.T) {
+	processesList = make(map[int]*Process)
+
+	stats, err := filepath.Glob("/proc/[0-9]*/stat")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range stats {
+		stat, err := os.ReadFile(path)
+
+		if err != nil {
+			continue
+		}
+
+		pid, _ := strconv.Atoi(filepath.Base(filepath.Dir(path)))
+		// parent pid is the second field after command name in parentheses
+		fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+		ppid, _ := strconv.Atoi(fields[1])
+
+		processesList[pid] = &Process{Pid: pid, PPid: ppid}
+	}
+
+	buildProcessesTree()
+}
+
+func TestDescendants(t *testing.T) {
+	processesList = map[int]*Process{
+		10: {Pid: 10, PPid: 1},
+		11: {Pid: 11, PPid: 10},
+		12: {Pid: 12, PPid: 10},
+		20: {Pid: 20, PPid: 11},
+		30: {Pid: 30, PPid: 1},
+	}
+
+	buildProcessesTree()
+
+	if got, want := descendants(10), []int{11, 12, 20}; !slices.Equal(got, want) {
+		t.Errorf("descendants(10) = %v, want %v", got, want)
+	}
+
+	if !isDescendant(20, 10) || isDescendant(30, 10) || isDescendant(10, 20) {
+		t.Error("isDescendant() returns wrong relation")
+	}
+}
+
+func TestKillTree(t *testing.T) {
+	cmd := exec.Command("/bin/sh", "-c", "sleep 30 & sleep 30 & wait")
+
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	go cmd.Wait()
+
+	var pids []int
+
+	for deadline := time.Now().Add(2 * time.Second); len(pids) < 3 && time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		loadTestProcesses(t)
+		pids = append([]int{cmd.Process.Pid}, descendants(cmd.Process.Pid)...)
+	}
+
+	if len(pids) < 3 {
+		cmd.Process.Kill()
+		t.Fatalf("shell didn't start children: %v", pids)
+	}
+
+	s := newTestService("tree-test")
+	s.KillMode = killMode(KILL_MODE_TREE)
+	s.process = processesList[cmd.Process.Pid]
+	s.instances = []*Process{s.process}
+
+	if err := s.kill(); err != nil {
+		t.Fatalf("kill() unexpected error: %v", err)
+	}
+
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if !slices.ContainsFunc(pids, processAlive) {
+			return
+		}
+	}
+
+	t.Errorf("processes of tree %v are alive after kill", pids)
+}
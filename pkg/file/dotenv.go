@@ -0,0 +1,138 @@
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvEntry is a single variable from dotenv file
+type EnvEntry struct {
+	Key   string
+	Value string
+	// false for single-quoted values which must be used literally
+	Expand bool
+}
+
+// ReadEnvFile parses dotenv-format file. Supported syntax:
+//
+//	# comment
+//	KEY=value
+//	export KEY=value
+//	KEY="value with \"escapes\"\n"
+//	KEY='literal value'
+func ReadEnvFile(filePath string) ([]EnvEntry, error) {
+	var entries []EnvEntry
+
+	f, err := os.Open(filePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line, _ = strings.CutPrefix(line, "export ")
+
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+
+		if !found || len(key) == 0 || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("%s:%d: expected 'KEY=value' line", filePath, lineNum)
+		}
+
+		entry, err := parseEnvValue(strings.TrimSpace(value))
+
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filePath, lineNum, err)
+		}
+
+		entry.Key = key
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// checkAfterQuote returns error if quoted value is followed by anything except inline comment
+func checkAfterQuote(rest string) error {
+	trimmed := strings.TrimLeft(rest, " \t")
+
+	if len(trimmed) == 0 || (len(trimmed) < len(rest) && strings.HasPrefix(trimmed, "#")) {
+		return nil
+	}
+
+	return fmt.Errorf("unexpected characters '%s' after quoted value", trimmed)
+}
+
+func parseEnvValue(value string) (EnvEntry, error) {
+	switch {
+	case strings.HasPrefix(value, "'"):
+		end := strings.Index(value[1:], "'")
+
+		if end < 0 {
+			return EnvEntry{}, fmt.Errorf("unterminated single-quoted value")
+		}
+
+		if err := checkAfterQuote(value[end+2:]); err != nil {
+			return EnvEntry{}, err
+		}
+
+		return EnvEntry{Value: value[1 : end+1]}, nil
+	case strings.HasPrefix(value, `"`):
+		var sb strings.Builder
+
+		for i := 1; i < len(value); i++ {
+			switch value[i] {
+			case '"':
+				if err := checkAfterQuote(value[i+1:]); err != nil {
+					return EnvEntry{}, err
+				}
+
+				return EnvEntry{Value: sb.String(), Expand: true}, nil
+			case '\\':
+				if i+1 == len(value) {
+					break
+				}
+
+				i++
+
+				switch value[i] {
+				case 'n':
+					sb.WriteByte('\n')
+				case 't':
+					sb.WriteByte('\t')
+				default:
+					sb.WriteByte(value[i])
+				}
+			default:
+				sb.WriteByte(value[i])
+			}
+		}
+
+		return EnvEntry{}, fmt.Errorf("unterminated double-quoted value")
+	}
+
+	// strip inline comment from unquoted value
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+
+	return EnvEntry{Value: value, Expand: true}, nil
+}
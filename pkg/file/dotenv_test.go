@@ -0,0 +1,133 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		expand  bool
+		wantErr bool
+	}{
+		{name: "empty", input: "", want: "", expand: true},
+		{name: "unquoted", input: "value", want: "value", expand: true},
+		{name: "unquoted with spaces", input: "two words", want: "two words", expand: true},
+		{name: "unquoted inline comment", input: "value # comment", want: "value", expand: true},
+		{name: "unquoted hash without space", input: "a#b", want: "a#b", expand: true},
+		{name: "unquoted reference", input: "${HOME}/bin", want: "${HOME}/bin", expand: true},
+		{name: "single quoted", input: "'literal ${HOME} \\n'", want: "literal ${HOME} \\n"},
+		{name: "single quoted with hash", input: "'a # b'", want: "a # b"},
+		{name: "single quoted inline comment", input: "'value' # comment", want: "value"},
+		{name: "single quoted trailing content", input: "'x'y", wantErr: true},
+		{name: "unterminated single quote", input: "'value", wantErr: true},
+		{name: "double quoted", input: `"two words"`, want: "two words", expand: true},
+		{name: "double quoted escapes", input: `"a\"b\\c\nd\te"`, want: "a\"b\\c\nd\te", expand: true},
+		{name: "double quoted unknown escape", input: `"\$HOME"`, want: "$HOME", expand: true},
+		{name: "double quoted inline comment", input: `"value"	# comment`, want: "value", expand: true},
+		{name: "double quoted trailing content", input: `"x"y`, wantErr: true},
+		{name: "double quoted comment without space", input: `"x"#y`, wantErr: true},
+		{name: "unterminated double quote", input: `"value`, wantErr: true},
+		{name: "double quote escaped at end", input: `"value\"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEnvValue(tt.input)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseEnvValue(%q) = %+v, want error", tt.input, got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parseEnvValue(%q) unexpected error: %v", tt.input, err)
+			}
+
+			if got.Value != tt.want || got.Expand != tt.expand {
+				t.Errorf("parseEnvValue(%q) = %q (expand %t), want %q (expand %t)",
+					tt.input, got.Value, got.Expand, tt.want, tt.expand)
+			}
+		})
+	}
+}
+
+func TestReadEnvFile(t *testing.T) {
+	content := strings.Join([]string{
+		"# comment",
+		"",
+		"PLAIN=value",
+		"  export EXPORTED = spaced # comment",
+		`QUOTED="line\nbreak"`,
+		"LITERAL='${NOT_EXPANDED}'",
+		"EMPTY=",
+	}, "\n")
+
+	path := filepath.Join(t.TempDir(), "test.env")
+
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadEnvFile(path)
+
+	if err != nil {
+		t.Fatalf("ReadEnvFile() unexpected error: %v", err)
+	}
+
+	want := []EnvEntry{
+		{Key: "PLAIN", Value: "value", Expand: true},
+		{Key: "EXPORTED", Value: "spaced", Expand: true},
+		{Key: "QUOTED", Value: "line\nbreak", Expand: true},
+		{Key: "LITERAL", Value: "${NOT_EXPANDED}"},
+		{Key: "EMPTY", Value: "", Expand: true},
+	}
+
+	if len(entries) != len(want) {
+		t.Fatalf("ReadEnvFile() = %+v, want %+v", entries, want)
+	}
+
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("ReadEnvFile() entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestReadEnvFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    string
+	}{
+		{name: "missing equals sign", content: "A=1\nNOVALUE\n", line: ":2:"},
+		{name: "empty key", content: "=value\n", line: ":1:"},
+		{name: "key with space", content: "MY KEY=value\n", line: ":1:"},
+		{name: "trailing content after quote", content: "A=1\nB='x'y\n", line: ":2:"},
+		{name: "unterminated quote", content: "A=\"x\n", line: ":1:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.env")
+
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := ReadEnvFile(path)
+
+			if err == nil || !strings.Contains(err.Error(), path+tt.line) {
+				t.Errorf("ReadEnvFile() error = %v, want error at line %s", err, tt.line)
+			}
+		})
+	}
+}
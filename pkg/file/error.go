@@ -1,5 +1,7 @@
 package file
 
+import "fmt"
+
 // directory error
 type ErrIsDir struct {
 	message string
@@ -14,3 +16,33 @@ func newIsDirError(message string) *ErrIsDir {
 func (e *ErrIsDir) Error() string {
 	return e.message
 }
+
+// undefined variable error
+type ErrUndefinedVar struct {
+	name string
+}
+
+func newUndefinedVarError(name string) *ErrUndefinedVar {
+	return &ErrUndefinedVar{
+		name: name,
+	}
+}
+
+func (e *ErrUndefinedVar) Error() string {
+	return fmt.Sprintf("variable '%s' is not defined", e.name)
+}
+
+// malformed variable reference error
+type ErrBadReference struct {
+	reference string
+}
+
+func newBadReferenceError(reference string) *ErrBadReference {
+	return &ErrBadReference{
+		reference: reference,
+	}
+}
+
+func (e *ErrBadReference) Error() string {
+	return fmt.Sprintf("malformed variable reference '%s'", e.reference)
+}
@@ -0,0 +1,133 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const fileRefPrefix string = "file:"
+
+// ExpandString replaces every `${NAME}` reference in s by the value returned by lookup
+// and every `${file:/path/to/secret}` reference by the content of the file without trailing newlines.
+// `$${` is an escaped `${` and stays in the result as is. Bare `$NAME` references are not expanded.
+func ExpandString(s string, lookup func(string) (string, bool)) (string, error) {
+	var sb strings.Builder
+
+	for {
+		i := strings.Index(s, "${")
+
+		if i < 0 {
+			sb.WriteString(s)
+
+			break
+		}
+
+		// '$${' is an escaped '${'
+		if i > 0 && s[i-1] == '$' {
+			sb.WriteString(s[:i-1])
+			sb.WriteString("${")
+			s = s[i+2:]
+
+			continue
+		}
+
+		end := strings.Index(s[i:], "}")
+
+		if end < 0 {
+			return "", newBadReferenceError(s[i:])
+		}
+
+		sb.WriteString(s[:i])
+
+		ref := s[i+2 : i+end]
+
+		value, err := resolveReference(ref, lookup)
+
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteString(value)
+		s = s[i+end+1:]
+	}
+
+	return sb.String(), nil
+}
+
+func resolveReference(ref string, lookup func(string) (string, bool)) (string, error) {
+	if path, ok := strings.CutPrefix(ref, fileRefPrefix); ok {
+		if len(path) == 0 {
+			return "", newBadReferenceError(fmt.Sprintf("${%s}", ref))
+		}
+
+		content, err := os.ReadFile(path)
+
+		if err != nil {
+			return "", fmt.Errorf("can't read secret file '%s': %w", path, err)
+		}
+
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+
+	if len(ref) == 0 {
+		return "", newBadReferenceError("${}")
+	}
+
+	value, ok := lookup(ref)
+
+	if !ok {
+		return "", newUndefinedVarError(ref)
+	}
+
+	return value, nil
+}
+
+// expandYamlNode expands references in all string scalars of YAML document tree.
+// Values at rawPaths aren't expanded. A path is a list of mapping keys from the document root
+// joined with dots, sequences are transparent, e.g. 'services_list.start_cmd'
+// matches 'start_cmd' of every service but not a 'start_cmd' key nested anywhere else.
+func expandYamlNode(node *yaml.Node, lookup func(string) (string, bool), rawPaths []string, path string) error {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, n := range node.Content {
+			if err := expandYamlNode(n, lookup, rawPaths, path); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		// mapping content is a flat list of key/value pairs, keys are never expanded
+		for i := 1; i < len(node.Content); i += 2 {
+			keyPath := node.Content[i-1].Value
+
+			if len(path) > 0 {
+				keyPath = path + "." + keyPath
+			}
+
+			if slices.Contains(rawPaths, keyPath) {
+				continue
+			}
+
+			if err := expandYamlNode(node.Content[i], lookup, rawPaths, keyPath); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if node.ShortTag() != "!!str" {
+			return nil
+		}
+
+		value, err := ExpandString(node.Value, lookup)
+
+		if err != nil {
+			return fmt.Errorf("line %d, column %d: %w", node.Line, node.Column, err)
+		}
+
+		node.Value = value
+	}
+
+	return nil
+}
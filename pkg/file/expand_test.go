@@ -0,0 +1,139 @@
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestExpandString(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+
+	if err := os.WriteFile(secret, []byte("s3cret\r\n\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	vars := map[string]string{"USER": "nanny", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		value, ok := vars[name]
+
+		return value, ok
+	}
+
+	tests := []struct {
+		name      string
+		input     string
+		want      string
+		undefined bool
+		malformed bool
+	}{
+		{name: "no references", input: "plain $USER text", want: "plain $USER text"},
+		{name: "variable", input: "${USER}@example.com", want: "nanny@example.com"},
+		{name: "several variables", input: "${USER}:${USER}", want: "nanny:nanny"},
+		{name: "empty variable", input: "a${EMPTY}b", want: "ab"},
+		{name: "escaped reference", input: "$${USER}", want: "${USER}"},
+		{name: "escaped and expanded", input: "$${USER}=${USER}", want: "${USER}=nanny"},
+		{name: "file reference", input: "pass=${file:" + secret + "}", want: "pass=s3cret"},
+		{name: "undefined variable", input: "${MISSING}", undefined: true},
+		{name: "unterminated reference", input: "abc ${USER", malformed: true},
+		{name: "empty reference", input: "${}", malformed: true},
+		{name: "empty file reference", input: "${file:}", malformed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandString(tt.input, lookup)
+
+			var undefinedErr *ErrUndefinedVar
+			var malformedErr *ErrBadReference
+
+			switch {
+			case tt.undefined:
+				if !errors.As(err, &undefinedErr) {
+					t.Fatalf("ExpandString(%q) error = %v, want ErrUndefinedVar", tt.input, err)
+				}
+			case tt.malformed:
+				if !errors.As(err, &malformedErr) {
+					t.Fatalf("ExpandString(%q) error = %v, want ErrBadReference", tt.input, err)
+				}
+			case err != nil:
+				t.Fatalf("ExpandString(%q) unexpected error: %v", tt.input, err)
+			case got != tt.want:
+				t.Errorf("ExpandString(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandStringMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing")
+
+	if _, err := ExpandString("${file:"+path+"}", os.LookupEnv); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("ExpandString() error = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestExpandYamlNodeRawPaths(t *testing.T) {
+	var document yaml.Node
+
+	input := `user: ${USER}
+services_list:
+  - start_cmd: echo ${APP_HOME:-/opt}
+    cmd_args: ["${USER}"]
+    description: ${USER}
+other:
+  start_cmd: ${USER}
+`
+
+	if err := yaml.Unmarshal([]byte(input), &document); err != nil {
+		t.Fatal(err)
+	}
+
+	lookup := func(name string) (string, bool) {
+		return "nanny", name == "USER"
+	}
+
+	rawPaths := []string{"services_list.start_cmd", "services_list.cmd_args"}
+
+	if err := expandYamlNode(&document, lookup, rawPaths, ""); err != nil {
+		t.Fatalf("expandYamlNode() unexpected error: %v", err)
+	}
+
+	var got struct {
+		User     string `yaml:"user"`
+		Services []struct {
+			StartCmd    string   `yaml:"start_cmd"`
+			CmdArgs     []string `yaml:"cmd_args"`
+			Description string   `yaml:"description"`
+		} `yaml:"services_list"`
+		Other struct {
+			StartCmd string `yaml:"start_cmd"`
+		} `yaml:"other"`
+	}
+
+	if err := document.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.User != "nanny" || got.Other.StartCmd != "nanny" {
+		t.Errorf("values outside of raw paths aren't expanded: %+v", got)
+	}
+
+	if len(got.Services) != 1 {
+		t.Fatalf("expandYamlNode() services = %+v", got.Services)
+	}
+
+	service := got.Services[0]
+
+	if service.StartCmd != "echo ${APP_HOME:-/opt}" || len(service.CmdArgs) != 1 || service.CmdArgs[0] != "${USER}" {
+		t.Errorf("values at raw paths are expanded: %+v", service)
+	}
+
+	if service.Description != "nanny" {
+		t.Errorf("expandYamlNode() description = %q, want %q", service.Description, "nanny")
+	}
+}
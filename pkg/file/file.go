@@ -28,10 +28,13 @@ func fileExists(filepath string) error {
 }
 
 // loadYamlFile read YAML file to byte slice than
-// yaml.Unmarshal decodes the first document found within the in byte slice and assigns decoded values into the out value.
-func LoadYamlFile(filePath string, out interface{}, logger log.Logger) error {
+// yaml.Unmarshal decodes the first document found within the in byte slice,
+// `${ENV_VAR}` and `${file:/path/to/secret}` references in string values are expanded
+// except values at rawPaths (see expandYamlNode) and decoded values are assigned into the out value.
+func LoadYamlFile(filePath string, out interface{}, logger log.Logger, rawPaths ...string) error {
 	var err error
 	var f []byte
+	var document yaml.Node
 
 	level.Debug(logger).Log("msg", "read yaml file", "value", filePath)
 
@@ -59,11 +62,28 @@ func LoadYamlFile(filePath string, out interface{}, logger log.Logger) error {
 		return err
 	}
 
-	if err := yaml.Unmarshal(f, out); err != nil {
+	if err := yaml.Unmarshal(f, &document); err != nil {
 		level.Error(logger).Log("msg", "error unmarshal yaml configuration", "error", err.Error())
 
 		return err
 	}
 
+	if err := expandYamlNode(&document, os.LookupEnv, rawPaths, ""); err != nil {
+		level.Error(logger).Log("msg", "error expand variables in yaml configuration", "error", err.Error())
+
+		return err
+	}
+
+	// empty file
+	if document.Kind == 0 {
+		return nil
+	}
+
+	if err := document.Decode(out); err != nil {
+		level.Error(logger).Log("msg", "error decode yaml configuration", "error", err.Error())
+
+		return err
+	}
+
 	return nil
 }
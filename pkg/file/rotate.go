@@ -0,0 +1,172 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maximum number of bytes read from the end of file by TailFile
+const TAIL_MAX_BYTES int64 = 64 * 1024
+
+var sizeSuffixes = []struct {
+	suffix     string
+	multiplier int64
+}{
+	// longest suffixes first
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"B", 1},
+}
+
+// ParseSize converts size string like "512", "100K", "10MB" or "1G" to number of bytes
+func ParseSize(size string) (int64, error) {
+	var multiplier int64 = 1
+
+	value := strings.ToUpper(strings.TrimSpace(size))
+
+	for _, s := range sizeSuffixes {
+		if number, ok := strings.CutSuffix(value, s.suffix); ok {
+			value = strings.TrimSpace(number)
+			multiplier = s.multiplier
+
+			break
+		}
+	}
+
+	number, err := strconv.ParseInt(value, 10, 64)
+
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", size)
+	}
+
+	return number * multiplier, nil
+}
+
+// RotateFile copies file which is bigger than maxSize into `<filePath>.1` and truncates it.
+// Older copies are shifted to `<filePath>.2` ... `<filePath>.<backups>`, the oldest one is removed.
+// The file is truncated instead of renamed because running process keeps writing into it.
+func RotateFile(filePath string, maxSize int64, backups int) (bool, error) {
+	fstat, err := os.Stat(filePath)
+
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	if maxSize <= 0 || fstat.Size() < maxSize {
+		return false, nil
+	}
+
+	if backups > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", filePath, backups))
+
+		for i := backups - 1; i > 0; i-- {
+			oldPath := fmt.Sprintf("%s.%d", filePath, i)
+
+			if _, err := os.Stat(oldPath); err == nil {
+				if err := os.Rename(oldPath, fmt.Sprintf("%s.%d", filePath, i+1)); err != nil {
+					return false, err
+				}
+			}
+		}
+
+		if err := copyFile(filePath, fmt.Sprintf("%s.1", filePath), fstat.Mode()); err != nil {
+			return false, err
+		}
+	}
+
+	return true, os.Truncate(filePath, 0)
+}
+
+func copyFile(src string, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+
+		return err
+	}
+
+	return out.Close()
+}
+
+// TailFile returns up to n last lines of file
+func TailFile(filePath string, n int) ([]string, error) {
+	return TailFileFrom(filePath, 0, n)
+}
+
+// TailFileFrom returns up to n last lines of file written after start offset.
+// Whole file is used if it's shorter than start, e.g. it was rotated or truncated.
+func TailFileFrom(filePath string, start int64, n int) ([]string, error) {
+	f, err := os.Open(filePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	fstat, err := f.Stat()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if start > fstat.Size() {
+		start = 0
+	}
+
+	offset := fstat.Size() - TAIL_MAX_BYTES
+
+	if offset < start {
+		offset = start
+	}
+
+	buf := make([]byte, fstat.Size()-offset)
+
+	if _, err := f.ReadAt(buf, offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	buf = bytes.TrimRight(buf, "\r\n")
+
+	if len(buf) == 0 {
+		return nil, nil
+	}
+
+	lines := strings.Split(string(buf), "\n")
+
+	// first line may be cut in the middle
+	if offset > start && len(lines) > 1 {
+		lines = lines[1:]
+	}
+
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	return lines, nil
+}
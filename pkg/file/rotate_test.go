@@ -0,0 +1,44 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestTailFileFrom(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stderr.log")
+	old := "old line 1\nold line 2\n"
+
+	if err := os.WriteFile(path, []byte(old+"new line 1\nnew line 2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		start int64
+		n     int
+		want  []string
+	}{
+		{name: "whole file", start: 0, n: 10, want: []string{"old line 1", "old line 2", "new line 1", "new line 2"}},
+		{name: "last lines", start: 0, n: 1, want: []string{"new line 2"}},
+		{name: "from offset", start: int64(len(old)), n: 10, want: []string{"new line 1", "new line 2"}},
+		{name: "nothing after offset", start: int64(len(old)) + 22, n: 10, want: nil},
+		{name: "offset after end of truncated file", start: 1 << 20, n: 2, want: []string{"new line 1", "new line 2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TailFileFrom(path, tt.start, tt.n)
+
+			if err != nil {
+				t.Fatalf("TailFileFrom() unexpected error: %v", err)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("TailFileFrom(%d, %d) = %q, want %q", tt.start, tt.n, got, tt.want)
+			}
+		})
+	}
+}
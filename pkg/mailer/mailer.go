@@ -2,10 +2,17 @@ package mailer
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
-	"net/smtp"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -15,7 +22,16 @@ type Mailer struct {
 	SmtpServer    string      `yaml:"mail_smtp_server"`
 	MailUser      string      `yaml:"mail_auth_user"`
 	MailPassword  string      `yaml:"mail_auth_password"`
+	TLS           string      `yaml:"mail_tls"`
+	CAFile        string      `yaml:"mail_ca_file"`
+	SkipVerify    bool        `yaml:"mail_insecure_skip_verify"`
+	CertFile      string      `yaml:"mail_cert_file"`
+	KeyFile       string      `yaml:"mail_key_file"`
+	AuthMethod    string      `yaml:"mail_auth_method"`
 	SubjectPrefix string      `yaml:"mail_subject_prefix"`
+	SubjectTmpl   string      `yaml:"mail_subject_template"`
+	TextTmpl      string      `yaml:"mail_text_template"`
+	HtmlTmpl      string      `yaml:"mail_html_template"`
 	Headers       *MailHeader `yaml:"general,inline"`
 	passwordRunes []rune      // most safe storage for password in memory
 	Logger        *log.Logger
@@ -32,7 +48,11 @@ type MailHeader struct {
 	Subject     string
 }
 
-const MAIL_DEFAULT_CONTENT_TYPE string = `text/plain; charset="utf-8"`
+const (
+	MAIL_DEFAULT_CONTENT_TYPE string = "multipart/alternative"
+	MAIL_TEXT_CONTENT_TYPE    string = `text/plain; charset="utf-8"`
+	MAIL_HTML_CONTENT_TYPE    string = `text/html; charset="utf-8"`
+)
 
 func (h *MailHeader) String() string {
 	return fmt.Sprintf("%+v", *h)
@@ -71,96 +91,225 @@ func (m *Mailer) CheckSettings() error {
 
 	}
 
-	return nil
+	return m.checkTLSSettings()
 }
 
-// return array of formatted strings which look like:
-// "Host '%hostname%' got error: %errorString%"
-func (m *Mailer) getErrorString(errorsStringArray []*error) []string {
-	var errorString []string
+// messageId returns unique 'Message-ID' header in domain of sender
+func (m *Mailer) messageId(now time.Time) string {
+	random := make([]byte, 8)
+	rand.Read(random)
 
-	level.Debug(*m.Logger).Log("msg", "create array of strings with errors")
+	domain, _ := os.Hostname()
 
-	hostname, _ := os.Hostname()
+	if i := strings.LastIndex(m.Headers.From, "@"); i >= 0 {
+		domain = strings.Trim(m.Headers.From[i+1:], "<> ")
+	}
 
-	for _, e := range errorsStringArray {
-		errorString = append(errorString, fmt.Sprintf("Host '%s' got error: %s\n", hostname, *e))
+	return fmt.Sprintf("<%d.%x@%s>", now.UnixNano(), random, domain)
+}
+
+// writePart writes body encoded as quoted-printable with CRLF line breaks
+func writePart(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
 	}
 
-	return errorString
+	return qp.Close()
+}
+
+// Attachment is file attached to email
+type Attachment struct {
+	Name        string
+	ContentType string
+	Content     []byte
 }
 
-func (m *Mailer) getErrorsBody(errorsArray []*error) string {
-	var mailBody string
+// writeBase64 writes data encoded as base64 with lines of 76 characters
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	for len(encoded) > 0 {
+		n := len(encoded)
+
+		if n > 76 {
+			n = 76
+		}
 
-	errorsStringArray := m.getErrorString(errorsArray)
+		if _, err := io.WriteString(w, encoded[:n]+"\r\n"); err != nil {
+			return err
+		}
+
+		encoded = encoded[n:]
+	}
+
+	return nil
+}
+
+// bodyPart returns headers and content of message's body.
+// With 'multipart/alternative' content type body has both text and html parts,
+// otherwise it has single part of 'mail_content_type'.
+func (m *Mailer) bodyPart(textBody string, htmlBody string) (textproto.MIMEHeader, []byte, error) {
+	var body bytes.Buffer
 
 	switch {
-	case strings.Contains(m.Headers.ContentType, "text/plain"):
-		mailBody = strings.Join(errorsStringArray, "\n")
+	case strings.Contains(m.Headers.ContentType, "multipart/alternative"):
+		w := multipart.NewWriter(&body)
+
+		for _, part := range []struct{ contentType, body string }{
+			// the last part is preferred by mail clients
+			{MAIL_TEXT_CONTENT_TYPE, textBody},
+			{MAIL_HTML_CONTENT_TYPE, htmlBody},
+		} {
+			pw, err := w.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {part.contentType},
+				"Content-Transfer-Encoding": {"quoted-printable"},
+			})
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if err := writePart(pw, part.body); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if err := w.Close(); err != nil {
+			return nil, nil, err
+		}
+
+		return textproto.MIMEHeader{
+			"Content-Type": {fmt.Sprintf("multipart/alternative; boundary=%q", w.Boundary())},
+		}, body.Bytes(), nil
 	case strings.Contains(m.Headers.ContentType, "text/html"):
-		mailBody = m.getErrorsHtml(errorsStringArray)
+		if err := writePart(&body, htmlBody); err != nil {
+			return nil, nil, err
+		}
+	default:
+		if err := writePart(&body, textBody); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	return mailBody
+	return textproto.MIMEHeader{
+		"Content-Type":              {m.Headers.ContentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	}, body.Bytes(), nil
 }
 
-func (m *Mailer) getErrorsHtml(errorsStringArray []string) string {
-	var htmlBody string
+// mixedPart returns body with attachments as 'multipart/mixed' part
+func mixedPart(header textproto.MIMEHeader, content []byte, attachments []*Attachment) (textproto.MIMEHeader, []byte, error) {
+	var body bytes.Buffer
 
-	level.Debug(*m.Logger).Log("msg", "create full html with errors")
+	w := multipart.NewWriter(&body)
+
+	pw, err := w.CreatePart(header)
+
+	if err != nil {
+		return nil, nil, err
+	}
 
-	for _, s := range errorsStringArray {
-		htmlBody += fmt.Sprintf("		<br>%s\n", s)
+	if _, err := pw.Write(content); err != nil {
+		return nil, nil, err
 	}
 
-	return fmt.Sprintf(`
-<html>
-	<head></head>
-	<body>
-%s
-	</body>
-</html>`, htmlBody)
+	for _, a := range attachments {
+		contentType := a.ContentType
+
+		if len(contentType) == 0 {
+			contentType = "application/octet-stream"
+		}
+
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := writeBase64(pw, a.Content); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	return textproto.MIMEHeader{
+		"Content-Type": {fmt.Sprintf("multipart/mixed; boundary=%q", w.Boundary())},
+	}, body.Bytes(), nil
 }
 
-func (m *Mailer) buildEmail(mailBodyString string) []byte {
+// buildEmail returns RFC 5322 message with CRLF line breaks.
+// Message with attachments is 'multipart/mixed' with body as the first part.
+func (m *Mailer) buildEmail(textBody string, htmlBody string, attachments []*Attachment) ([]byte, error) {
 	var buf bytes.Buffer
 
-	buf.WriteString(fmt.Sprintf("From: %s\n", m.Headers.From))
-	buf.WriteString(fmt.Sprintf("To: %s\n", strings.Join(m.Headers.To, "; ")))
-	buf.WriteString(fmt.Sprintf("Subject: %s\n", m.Headers.Subject))
-	buf.WriteString(fmt.Sprintf("Content-Type: %s\n", m.Headers.ContentType))
-	buf.WriteString(fmt.Sprintf("\n%s", mailBodyString))
+	now := time.Now()
+
+	header := func(key string, value string) {
+		buf.WriteString(fmt.Sprintf("%s: %s\r\n", key, value))
+	}
+
+	partHeader, content, err := m.bodyPart(textBody, htmlBody)
+
+	if err != nil {
+		return nil, err
+	}
 
-	return buf.Bytes()
+	if len(attachments) > 0 {
+		if partHeader, content, err = mixedPart(partHeader, content, attachments); err != nil {
+			return nil, err
+		}
+	}
+
+	header("From", m.Headers.From)
+	header("To", strings.Join(m.Headers.To, ", "))
+	// non-ASCII subject is encoded according to RFC 2047
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Headers.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", m.messageId(now))
+	header("MIME-Version", "1.0")
+	header("Content-Type", partHeader.Get("Content-Type"))
+
+	if encoding := partHeader.Get("Content-Transfer-Encoding"); len(encoding) > 0 {
+		header("Content-Transfer-Encoding", encoding)
+	}
 
+	buf.WriteString("\r\n")
+	buf.Write(content)
+
+	return buf.Bytes(), nil
 }
 
-func (m *Mailer) SendHtmlEmail(errors []*error) error {
+// SendEmail sends email with text and html bodies according to 'mail_content_type' and attachments
+func (m *Mailer) SendEmail(textBody string, htmlBody string, attachments []*Attachment) error {
 	var err error
-	var mailBodyString string
-	var smtpAuth smtp.Auth
 
-	level.Debug(*m.Logger).Log("msg", "send html email with errors")
+	level.Debug(*m.Logger).Log("msg", "send email with errors")
 
 	if len(m.Headers.ContentType) == 0 {
 		m.Headers.ContentType = MAIL_DEFAULT_CONTENT_TYPE
 	}
 
-	if len(m.MailUser) > 0 && m.passwordRunes != nil {
-		smtpAuth = smtp.PlainAuth("", m.MailUser, string(m.passwordRunes), strings.Split(m.SmtpServer, ":")[0])
-	}
-
-	mailBodyString = m.getErrorsBody(errors)
-
 	// Prepare message as RFC-822 formatted
-	messageBytes := m.buildEmail(mailBodyString)
+	messageBytes, err := m.buildEmail(textBody, htmlBody, attachments)
+
+	if err != nil {
+		return err
+	}
 
 	level.Debug(*m.Logger).Log("msg", "send email", "server", m.SmtpServer,
 		"from", m.Headers.From, "to", fmt.Sprintf("%+v", m.Headers.To),
 		"value", string(messageBytes))
 
-	if err = smtp.SendMail(m.SmtpServer, smtpAuth, m.Headers.From, m.Headers.To, messageBytes); err != nil {
+	if err = m.sendMail(messageBytes); err != nil {
 		level.Error(*m.Logger).Log("msg", "got error when try to send email", "error", err.Error())
 	}
 
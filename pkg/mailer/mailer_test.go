@@ -0,0 +1,177 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// readParts returns parts of multipart body with decoded content
+func readParts(t *testing.T, contentType string, body io.Reader) ([]*multipart.Part, [][]byte) {
+	t.Helper()
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		t.Fatalf("content type %q isn't multipart: %v", contentType, err)
+	}
+
+	var parts []*multipart.Part
+	var contents [][]byte
+
+	r := multipart.NewReader(body, params["boundary"])
+
+	for {
+		part, err := r.NextRawPart()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var content io.Reader = part
+
+		switch part.Header.Get("Content-Transfer-Encoding") {
+		case "quoted-printable":
+			content = quotedprintable.NewReader(part)
+		case "base64":
+			content = base64.NewDecoder(base64.StdEncoding, part)
+		}
+
+		data, err := io.ReadAll(content)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parts = append(parts, part)
+		contents = append(contents, data)
+	}
+
+	return parts, contents
+}
+
+func TestBuildEmail(t *testing.T) {
+	m := &Mailer{Headers: &MailHeader{
+		From:        "nanny@example.com",
+		To:          []string{"ops@example.com", "dev@example.com"},
+		ContentType: MAIL_DEFAULT_CONTENT_TYPE,
+		Subject:     "VM | 'сервис' DOWN",
+	}}
+
+	text := "service is down\nsecond line"
+	html := "<p>service is down</p>"
+	attachment := &Attachment{Name: "svc-diagnostics.txt", ContentType: "text/plain; charset=utf-8",
+		Content: bytes.Repeat([]byte("diagnostic output\n"), 10)}
+
+	data, err := m.buildEmail(text, html, []*Attachment{attachment})
+
+	if err != nil {
+		t.Fatalf("buildEmail() unexpected error: %v", err)
+	}
+
+	if bytes.Count(data, []byte("\n")) != bytes.Count(data, []byte("\r\n")) {
+		t.Errorf("buildEmail() message has line breaks without CR")
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+
+	if err != nil {
+		t.Fatalf("can't parse message: %v", err)
+	}
+
+	if strings.ContainsRune(msg.Header.Get("Subject"), 'с') {
+		t.Errorf("Subject %q isn't encoded", msg.Header.Get("Subject"))
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+
+	if err != nil || subject != m.Headers.Subject {
+		t.Errorf("Subject = %q (%v), want %q", subject, err, m.Headers.Subject)
+	}
+
+	if to, err := msg.Header.AddressList("To"); err != nil || len(to) != 2 {
+		t.Errorf("To = %v (%v), want 2 addresses", to, err)
+	}
+
+	if !strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("Message-ID = %q, want domain of sender", msg.Header.Get("Message-ID"))
+	}
+
+	parts, contents := readParts(t, msg.Header.Get("Content-Type"), msg.Body)
+
+	if len(parts) != 2 {
+		t.Fatalf("multipart/mixed has %d parts, want 2", len(parts))
+	}
+
+	if _, params, _ := mime.ParseMediaType(parts[1].Header.Get("Content-Disposition")); params["filename"] != attachment.Name {
+		t.Errorf("attachment filename = %q, want %q", params["filename"], attachment.Name)
+	}
+
+	if !bytes.Equal(contents[1], attachment.Content) {
+		t.Errorf("attachment content = %q, want %q", contents[1], attachment.Content)
+	}
+
+	bodyParts, bodies := readParts(t, parts[0].Header.Get("Content-Type"), bytes.NewReader(contents[0]))
+
+	if len(bodyParts) != 2 {
+		t.Fatalf("multipart/alternative has %d parts, want 2", len(bodyParts))
+	}
+
+	for i, want := range []struct{ contentType, body string }{
+		{MAIL_TEXT_CONTENT_TYPE, strings.ReplaceAll(text, "\n", "\r\n")},
+		{MAIL_HTML_CONTENT_TYPE, html},
+	} {
+		if got := bodyParts[i].Header.Get("Content-Type"); got != want.contentType {
+			t.Errorf("part %d Content-Type = %q, want %q", i, got, want.contentType)
+		}
+
+		if string(bodies[i]) != want.body {
+			t.Errorf("part %d body = %q, want %q", i, bodies[i], want.body)
+		}
+	}
+}
+
+func TestBuildEmailSinglePart(t *testing.T) {
+	m := &Mailer{Headers: &MailHeader{
+		From:        "nanny@example.com",
+		To:          []string{"ops@example.com"},
+		ContentType: "text/html",
+		Subject:     "plain subject",
+	}}
+
+	data, err := m.buildEmail("text", "<p>html</p>", nil)
+
+	if err != nil {
+		t.Fatalf("buildEmail() unexpected error: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+
+	if err != nil {
+		t.Fatalf("can't parse message: %v", err)
+	}
+
+	if got := msg.Header.Get("Subject"); got != "plain subject" {
+		t.Errorf("Subject = %q, want %q", got, "plain subject")
+	}
+
+	if got := msg.Header.Get("Content-Type"); got != "text/html" {
+		t.Errorf("Content-Type = %q, want %q", got, "text/html")
+	}
+
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+
+	if err != nil || string(body) != "<p>html</p>" {
+		t.Errorf("body = %q (%v), want %q", body, err, "<p>html</p>")
+	}
+}
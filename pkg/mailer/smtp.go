@@ -0,0 +1,296 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// values of 'mail_tls':
+// 'none' - plain connection without STARTTLS,
+// 'starttls' - STARTTLS if server supports it,
+// 'required' - STARTTLS is required,
+// 'implicit' - TLS connection (SMTPS, usually port 465)
+const (
+	MAIL_TLS_NONE         string = "none"
+	MAIL_TLS_STARTTLS     string = "starttls"
+	MAIL_TLS_REQUIRED     string = "required"
+	MAIL_TLS_IMPLICIT     string = "implicit"
+	MAIL_DEFAULT_TLS_MODE string = MAIL_TLS_STARTTLS
+)
+
+const (
+	MAIL_AUTH_PLAIN      string        = "plain"
+	MAIL_AUTH_LOGIN      string        = "login"
+	MAIL_AUTH_CRAM_MD5   string        = "cram-md5"
+	MAIL_DEFAULT_TIMEOUT time.Duration = 30 * time.Second
+)
+
+var (
+	mailTLSModes    = []string{MAIL_TLS_NONE, MAIL_TLS_STARTTLS, MAIL_TLS_REQUIRED, MAIL_TLS_IMPLICIT}
+	mailAuthMethods = []string{MAIL_AUTH_PLAIN, MAIL_AUTH_LOGIN, MAIL_AUTH_CRAM_MD5}
+)
+
+func (m *Mailer) tlsMode() string {
+	if len(m.TLS) == 0 {
+		return MAIL_DEFAULT_TLS_MODE
+	}
+
+	return m.TLS
+}
+
+func (m *Mailer) authMethod() string {
+	if len(m.AuthMethod) == 0 {
+		return MAIL_AUTH_PLAIN
+	}
+
+	return m.AuthMethod
+}
+
+// checkTLSSettings returns error if TLS mode or authentication method isn't supported
+func (m *Mailer) checkTLSSettings() error {
+	switch {
+	case !slices.Contains(mailTLSModes, m.tlsMode()):
+		return &ErrBadMailSettings{fmt.Sprintf("mail settings have unsupported 'mail_tls' value '%s'. supported values: %s",
+			m.TLS, strings.Join(mailTLSModes, ", "))}
+	case !slices.Contains(mailAuthMethods, m.authMethod()):
+		return &ErrBadMailSettings{fmt.Sprintf("mail settings have unsupported 'mail_auth_method' value '%s'. supported values: %s",
+			m.AuthMethod, strings.Join(mailAuthMethods, ", "))}
+	case len(m.KeyFile) > 0 && len(m.CertFile) == 0:
+		return &ErrBadMailSettings{"mail settings have 'mail_key_file' without 'mail_cert_file'"}
+	}
+
+	return nil
+}
+
+// tlsConfig returns TLS settings with custom CA and client certificate
+func (m *Mailer) tlsConfig(host string) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: m.SkipVerify,
+	}
+
+	if len(m.CAFile) > 0 {
+		pem, err := os.ReadFile(m.CAFile)
+
+		if err != nil {
+			return nil, fmt.Errorf("can't read 'mail_ca_file': %w", err)
+		}
+
+		config.RootCAs = x509.NewCertPool()
+
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("'mail_ca_file' '%s' doesn't contain PEM certificates", m.CAFile)
+		}
+	}
+
+	if len(m.CertFile) > 0 {
+		// key may be in the same file as certificate
+		keyFile := m.KeyFile
+
+		if len(keyFile) == 0 {
+			keyFile = m.CertFile
+		}
+
+		cert, err := tls.LoadX509KeyPair(m.CertFile, keyFile)
+
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate: %w", err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// dial connects to SMTP server according to 'mail_tls'
+func (m *Mailer) dial() (*smtp.Client, error) {
+	host, _, err := net.SplitHostPort(m.SmtpServer)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := m.tlsConfig(host)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: MAIL_DEFAULT_TIMEOUT}
+
+	var conn net.Conn
+
+	level.Debug(*m.Logger).Log("msg", "connect to smtp server", "server", m.SmtpServer, "tls", m.tlsMode())
+
+	if m.tlsMode() == MAIL_TLS_IMPLICIT {
+		conn, err = tls.DialWithDialer(dialer, "tcp", m.SmtpServer, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", m.SmtpServer)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// whole session including sending of message should be completed within timeout
+	conn.SetDeadline(time.Now().Add(MAIL_DEFAULT_TIMEOUT))
+
+	c, err := smtp.NewClient(conn, host)
+
+	if err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	m.traceClient(c, conn)
+
+	if hostname, err := os.Hostname(); err == nil {
+		if err := c.Hello(hostname); err != nil {
+			c.Close()
+
+			return nil, err
+		}
+	}
+
+	if m.tlsMode() != MAIL_TLS_STARTTLS && m.tlsMode() != MAIL_TLS_REQUIRED {
+		return c, nil
+	}
+
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		if m.tlsMode() == MAIL_TLS_REQUIRED {
+			c.Close()
+
+			return nil, fmt.Errorf("smtp server '%s' doesn't support STARTTLS", m.SmtpServer)
+		}
+
+		level.Debug(*m.Logger).Log("msg", "smtp server doesn't support STARTTLS. send email without TLS",
+			"server", m.SmtpServer)
+
+		return c, nil
+	}
+
+	if err := c.StartTLS(tlsConfig); err != nil {
+		c.Close()
+
+		return nil, err
+	}
+
+	level.Debug(*m.Logger).Log("msg", "smtp connection is upgraded to TLS", "server", m.SmtpServer)
+
+	// STARTTLS creates new text connection over TLS
+	m.traceClient(c, conn)
+
+	return c, nil
+}
+
+// auth returns authentication of 'mail_auth_method' or nil if user or password isn't set
+func (m *Mailer) auth(host string) smtp.Auth {
+	if len(m.MailUser) == 0 || m.passwordRunes == nil {
+		return nil
+	}
+
+	switch m.authMethod() {
+	case MAIL_AUTH_LOGIN:
+		return &loginAuth{m.MailUser, string(m.passwordRunes), host}
+	case MAIL_AUTH_CRAM_MD5:
+		return smtp.CRAMMD5Auth(m.MailUser, string(m.passwordRunes))
+	default:
+		return smtp.PlainAuth("", m.MailUser, string(m.passwordRunes), host)
+	}
+}
+
+// sendMail sends message like smtp.SendMail, but connects according to TLS settings
+func (m *Mailer) sendMail(message []byte) error {
+	c, err := m.dial()
+
+	if err != nil {
+		return err
+	}
+
+	defer c.Close()
+
+	host, _, _ := net.SplitHostPort(m.SmtpServer)
+
+	if auth := m.auth(host); auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp server '%s' doesn't support AUTH", m.SmtpServer)
+		}
+
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(m.Headers.From); err != nil {
+		return err
+	}
+
+	for _, to := range m.Headers.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// loginAuth is 'LOGIN' authentication which isn't implemented by net/smtp
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	// like smtp.PlainAuth, credentials are sent only over TLS or to localhost
+	if !server.TLS && !slices.Contains([]string{"localhost", "127.0.0.1", "::1"}, server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge '%s'", fromServer)
+	}
+}
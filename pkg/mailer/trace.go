@@ -0,0 +1,91 @@
+package mailer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// traceConn logs SMTP conversation in debug mode.
+// Credentials of authentication and body of message aren't logged.
+type traceConn struct {
+	r      *bufio.Reader
+	w      *bufio.Writer
+	closer io.Closer
+	logger *log.Logger
+	auth   bool
+	data   bool
+}
+
+// traceClient replaces text connection of client with traced one.
+// It should be called again after STARTTLS, because client creates new text connection.
+func (m *Mailer) traceClient(c *smtp.Client, closer io.Closer) {
+	c.Text = textproto.NewConn(&traceConn{
+		r:      c.Text.Reader.R,
+		w:      c.Text.Writer.W,
+		closer: closer,
+		logger: m.Logger,
+	})
+}
+
+func (t *traceConn) log(prefix string, p []byte) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\r\n"), "\r\n") {
+		level.Debug(*t.logger).Log("msg", "smtp conversation", "value", fmt.Sprintf("%s %s", prefix, line))
+	}
+}
+
+func (t *traceConn) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+
+	if n > 0 {
+		t.log("S:", p[:n])
+
+		code := strings.SplitN(string(p[:n]), " ", 2)[0]
+
+		// authentication ends when server doesn't ask for next credentials
+		if t.auth && code != "334" {
+			t.auth = false
+		}
+
+		t.data = code == "354"
+	}
+
+	return n, err
+}
+
+func (t *traceConn) Write(p []byte) (int, error) {
+	line := string(p)
+
+	switch {
+	case t.data:
+		// body of message is logged by SendEmail
+		if strings.HasSuffix(line, "\r\n.\r\n") || line == ".\r\n" {
+			t.log("C:", []byte("<message> ."))
+		}
+	case t.auth:
+		t.log("C:", []byte("***"))
+	case strings.HasPrefix(strings.ToUpper(line), "AUTH "):
+		t.auth = true
+		t.log("C:", []byte(strings.Join(strings.Fields(line)[:2], " ")+" ***"))
+	default:
+		t.log("C:", p)
+	}
+
+	n, err := t.w.Write(p)
+
+	if err != nil {
+		return n, err
+	}
+
+	return n, t.w.Flush()
+}
+
+func (t *traceConn) Close() error {
+	return t.closer.Close()
+}
@@ -0,0 +1,31 @@
+package notifier
+
+import "fmt"
+
+// ErrNotify is error of notification's delivery, so notification can be sent again later
+type ErrNotify struct {
+	notifier string
+	message  string
+}
+
+func (e *ErrNotify) String() string {
+	return fmt.Sprintf("%+v", *e)
+}
+
+func (e *ErrNotify) Error() string {
+	return fmt.Sprintf("notifier '%s' failed: %s", e.notifier, e.message)
+}
+
+// ErrTemplate is error of notification's rendering, it isn't fixed by sending again
+type ErrTemplate struct {
+	notifier string
+	message  string
+}
+
+func (e *ErrTemplate) String() string {
+	return fmt.Sprintf("%+v", *e)
+}
+
+func (e *ErrTemplate) Error() string {
+	return fmt.Sprintf("notifier '%s' can't render notification: %s", e.notifier, e.message)
+}
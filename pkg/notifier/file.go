@@ -0,0 +1,48 @@
+package notifier
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// File appends notifications to file in JSON Lines format
+type File struct {
+	config *Config
+	logger *log.Logger
+}
+
+func (n *File) Name() string {
+	return n.config.Name
+}
+
+func (n *File) Notify(msg *Message) error {
+	line, err := json.Marshal(newJsonMessage(msg))
+
+	if err != nil {
+		return err
+	}
+
+	level.Debug(*n.logger).Log("msg", "append notification to file", "notifier", n.config.Name,
+		"value", n.config.Path)
+
+	f, err := os.OpenFile(n.config.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+
+	if err != nil {
+		return &ErrNotify{n.config.Name, err.Error()}
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+
+		return &ErrNotify{n.config.Name, err.Error()}
+	}
+
+	if err := f.Close(); err != nil {
+		return &ErrNotify{n.config.Name, err.Error()}
+	}
+
+	return nil
+}
@@ -0,0 +1,310 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ashokhin/autosys-nanny/pkg/mailer"
+)
+
+const (
+	TYPE_SMTP    string = "smtp"
+	TYPE_WEBHOOK string = "webhook"
+	TYPE_SYSLOG  string = "syslog"
+	TYPE_FILE    string = "file"
+	// name of notifiers created from 'mailing_list' of services and 'general' section
+	MAILING_LIST_NAME string = "mailing_list"
+)
+
+var supportedTypes = []string{TYPE_SMTP, TYPE_WEBHOOK, TYPE_SYSLOG, TYPE_FILE}
+
+// Notifier delivers notifications about errors to one destination
+type Notifier interface {
+	Name() string
+	Notify(msg *Message) error
+}
+
+// Message is notification about errors of service or of nanny itself.
+// It's also data of templates.
+type Message struct {
+	Hostname string
+	// empty for nanny's own errors
+	Service     string
+	Description string
+	// state of service: 'restarted', 'down', 'restart-failed', 'still-down', 'recovered', 'suppressed' or 'digest'
+	State   string
+	Subject string
+	Errors  []*error
+	Time    time.Time
+	// number of service's starts by nanny
+	RestartCount int
+	// process of service found by nanny, nil if service wasn't running
+	Process *ProcessInfo
+	// last lines of service's stderr log
+	LogTail []string
+	// exit status of process which exited right after start, empty if it isn't known
+	ExitStatus string
+	// last lines of output of 'stop_cmd'
+	StopOutput []string
+	// output of service's diagnostic command if it isn't attached
+	Diagnostics []string
+	// files attached to emails
+	Attachments []*mailer.Attachment
+	// messages grouped into digest. Errors of digest are its summary
+	Digest []*Message
+}
+
+// ProcessInfo is process of service in message
+type ProcessInfo struct {
+	Pid       int
+	PPid      int
+	Cmdline   string
+	StartTime time.Time
+	Uptime    time.Duration
+	// resident set size in bytes
+	Rss int64
+}
+
+func (p *ProcessInfo) String() string {
+	return fmt.Sprintf("%+v", *p)
+}
+
+func (m *Message) String() string {
+	return fmt.Sprintf("%+v", *m)
+}
+
+// error strings of message
+func (m *Message) ErrorStrings() []string {
+	var errorStrings []string
+
+	for _, e := range m.Errors {
+		errorStrings = append(errorStrings, (*e).Error())
+	}
+
+	return errorStrings
+}
+
+// messageJson is representation of message in outbox: errors are stored as strings
+type messageJson struct {
+	Hostname     string               `json:"hostname"`
+	Service      string               `json:"service,omitempty"`
+	Description  string               `json:"description,omitempty"`
+	State        string               `json:"state,omitempty"`
+	Subject      string               `json:"subject"`
+	Errors       []string             `json:"errors"`
+	Time         time.Time            `json:"time"`
+	RestartCount int                  `json:"restart_count,omitempty"`
+	Process      *ProcessInfo         `json:"process,omitempty"`
+	LogTail      []string             `json:"log_tail,omitempty"`
+	ExitStatus   string               `json:"exit_status,omitempty"`
+	StopOutput   []string             `json:"stop_output,omitempty"`
+	Diagnostics  []string             `json:"diagnostics,omitempty"`
+	Attachments  []*mailer.Attachment `json:"attachments,omitempty"`
+	Digest       []*messageJson       `json:"digest,omitempty"`
+}
+
+func (m *Message) toJson() *messageJson {
+	mj := &messageJson{
+		Hostname:     m.Hostname,
+		Service:      m.Service,
+		Description:  m.Description,
+		State:        m.State,
+		Subject:      m.Subject,
+		Errors:       m.ErrorStrings(),
+		Time:         m.Time,
+		RestartCount: m.RestartCount,
+		Process:      m.Process,
+		LogTail:      m.LogTail,
+		ExitStatus:   m.ExitStatus,
+		StopOutput:   m.StopOutput,
+		Diagnostics:  m.Diagnostics,
+		Attachments:  m.Attachments,
+	}
+
+	for _, dm := range m.Digest {
+		mj.Digest = append(mj.Digest, dm.toJson())
+	}
+
+	return mj
+}
+
+func (mj *messageJson) toMessage() *Message {
+	m := &Message{
+		Hostname:     mj.Hostname,
+		Service:      mj.Service,
+		Description:  mj.Description,
+		State:        mj.State,
+		Subject:      mj.Subject,
+		Time:         mj.Time,
+		RestartCount: mj.RestartCount,
+		Process:      mj.Process,
+		LogTail:      mj.LogTail,
+		ExitStatus:   mj.ExitStatus,
+		StopOutput:   mj.StopOutput,
+		Diagnostics:  mj.Diagnostics,
+		Attachments:  mj.Attachments,
+	}
+
+	for _, e := range mj.Errors {
+		err := errors.New(e)
+		m.Errors = append(m.Errors, &err)
+	}
+
+	for _, dmj := range mj.Digest {
+		m.Digest = append(m.Digest, dmj.toMessage())
+	}
+
+	return m
+}
+
+func (m *Message) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.toJson())
+}
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var mj messageJson
+
+	if err := json.Unmarshal(data, &mj); err != nil {
+		return err
+	}
+
+	*m = *mj.toMessage()
+
+	return nil
+}
+
+// FormatDuration returns duration rounded to minutes, or to seconds if it's shorter than minute
+func FormatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return d.Truncate(time.Second).String()
+	}
+
+	return strings.TrimSuffix(d.Truncate(time.Minute).String(), "0s")
+}
+
+// ErrorsCount returns number of errors in message or in messages of digest
+func (m *Message) ErrorsCount() int {
+	count := 0
+
+	for _, dm := range m.messages() {
+		count += len(dm.Errors)
+	}
+
+	return count
+}
+
+// attachments returns files attached to message or to messages of digest
+func (m *Message) attachments() []*mailer.Attachment {
+	var attachments []*mailer.Attachment
+
+	for _, dm := range m.messages() {
+		attachments = append(attachments, dm.Attachments...)
+	}
+
+	return attachments
+}
+
+// messages returns messages grouped into digest or message itself
+func (m *Message) messages() []*Message {
+	if len(m.Digest) > 0 {
+		return m.Digest
+	}
+
+	return []*Message{m}
+}
+
+// RecipientKey returns key of notifier's recipients: sorted emails of SMTP notifier or name of other notifier
+func RecipientKey(n Notifier) string {
+	if s, ok := n.(*Smtp); ok {
+		to := slices.Clone(s.to)
+		slices.Sort(to)
+
+		return fmt.Sprintf("%s:%s", TYPE_SMTP, strings.Join(slices.Compact(to), ","))
+	}
+
+	return n.Name()
+}
+
+// Config is item of 'notifiers' section. Properties are used according to 'type'.
+type Config struct {
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type"`
+	NannyErrors bool   `yaml:"nanny_errors"`
+	// smtp: recipients, settings are taken from 'general' section
+	MailList []string `yaml:"mailing_list"`
+	// webhook
+	URL     string            `yaml:"url"`
+	Format  string            `yaml:"format"`
+	Headers map[string]string `yaml:"headers"`
+	Timeout time.Duration     `yaml:"timeout"`
+	// syslog
+	Network  string `yaml:"network"`
+	Address  string `yaml:"address"`
+	Facility string `yaml:"facility"`
+	Tag      string `yaml:"tag"`
+	// file
+	Path string `yaml:"path"`
+}
+
+func (c *Config) String() string {
+	return fmt.Sprintf("%+v", *c)
+}
+
+// validate required properties of notifier's type
+func (c *Config) UnmarshalYAML(value *yaml.Node) error {
+	type rawConfig Config
+
+	if err := value.Decode((*rawConfig)(c)); err != nil {
+		return err
+	}
+
+	var err error
+
+	switch {
+	case len(c.Name) == 0:
+		err = fmt.Errorf("notifier should have 'name'")
+	case c.Name == MAILING_LIST_NAME:
+		err = fmt.Errorf("notifier name '%s' is reserved for 'mailing_list' of services", c.Name)
+	case !slices.Contains(supportedTypes, c.Type):
+		err = fmt.Errorf("notifier '%s' has unsupported type '%s'. supported values: %s",
+			c.Name, c.Type, strings.Join(supportedTypes, ", "))
+	case c.Type == TYPE_SMTP && len(c.MailList) == 0:
+		err = fmt.Errorf("notifier '%s' should have 'mailing_list'", c.Name)
+	case c.Type == TYPE_WEBHOOK && len(c.URL) == 0:
+		err = fmt.Errorf("notifier '%s' should have 'url'", c.Name)
+	case c.Type == TYPE_WEBHOOK && !slices.Contains(webhookFormats, c.webhookFormat()):
+		err = fmt.Errorf("notifier '%s' has unsupported format '%s'. supported values: %s",
+			c.Name, c.Format, strings.Join(webhookFormats, ", "))
+	case c.Type == TYPE_SYSLOG && !c.validFacility():
+		err = fmt.Errorf("notifier '%s' has unsupported syslog facility '%s'", c.Name, c.Facility)
+	case c.Type == TYPE_FILE && len(c.Path) == 0:
+		err = fmt.Errorf("notifier '%s' should have 'path'", c.Name)
+	}
+
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+
+	return nil
+}
+
+// New creates notifier from config. SMTP notifier sends emails with settings of mailer rendered by templates.
+func New(config *Config, m *mailer.Mailer, t *Templates, logger *log.Logger) Notifier {
+	switch config.Type {
+	case TYPE_SMTP:
+		return NewSmtp(config.Name, m, t, config.MailList)
+	case TYPE_WEBHOOK:
+		return &Webhook{config: config, logger: logger}
+	case TYPE_SYSLOG:
+		return &Syslog{config: config, logger: logger}
+	default:
+		return &File{config: config, logger: logger}
+	}
+}
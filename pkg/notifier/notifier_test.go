@@ -0,0 +1,148 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"gopkg.in/yaml.v3"
+)
+
+func testMessage() *Message {
+	err := errors.New("service 'app' start failed")
+
+	return &Message{
+		Hostname: "host1",
+		Service:  "app",
+		Subject:  "host1: app errors",
+		Errors:   []*error{&err},
+		Time:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	tests := []struct {
+		config string
+		err    string
+	}{
+		{"{name: ops, type: file, path: /tmp/ops.jsonl}", ""},
+		{"{type: file, path: /tmp/ops.jsonl}", "should have 'name'"},
+		{"{name: ops, type: pager}", "unsupported type 'pager'"},
+		{"{name: ops, type: smtp}", "should have 'mailing_list'"},
+		{"{name: ops, type: webhook}", "should have 'url'"},
+		{"{name: ops, type: webhook, url: 'http://localhost', format: xml}", "unsupported format 'xml'"},
+		{"{name: ops, type: syslog, facility: kernel}", "unsupported syslog facility 'kernel'"},
+		{"{name: ops, type: file}", "should have 'path'"},
+		{"{name: mailing_list, type: smtp, mailing_list: [ops@example.com]}", "is reserved"},
+	}
+
+	for _, test := range tests {
+		var c Config
+
+		err := yaml.Unmarshal([]byte(test.config), &c)
+
+		switch {
+		case len(test.err) == 0 && err != nil:
+			t.Errorf("config %s: unexpected error: %v", test.config, err)
+		case len(test.err) > 0 && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("config %s: error = %v, want %q", test.config, err, test.err)
+		}
+	}
+}
+
+func TestFileNotify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.jsonl")
+	logger := log.NewNopLogger()
+	n := New(&Config{Name: "ops", Type: TYPE_FILE, Path: path}, nil, nil, &logger)
+
+	for i := 0; i < 2; i++ {
+		if err := n.Notify(testMessage()); err != nil {
+			t.Fatalf("Notify() unexpected error: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	if len(lines) != 2 {
+		t.Fatalf("file contains %d lines, want 2", len(lines))
+	}
+
+	var msg jsonMessage
+
+	if err := json.Unmarshal([]byte(lines[0]), &msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Service != "app" || msg.Hostname != "host1" || !slices.Equal(msg.Errors, []string{"service 'app' start failed"}) {
+		t.Errorf("notification = %+v, want message of service 'app'", msg)
+	}
+}
+
+func TestWebhookNotify(t *testing.T) {
+	var body map[string]string
+	var authorization string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+	}))
+	defer server.Close()
+
+	logger := log.NewNopLogger()
+	config := &Config{Name: "chat", Type: TYPE_WEBHOOK, URL: server.URL, Format: WEBHOOK_FORMAT_SLACK,
+		Headers: map[string]string{"Authorization": "Bearer token"}}
+
+	if err := New(config, nil, nil, &logger).Notify(testMessage()); err != nil {
+		t.Fatalf("Notify() unexpected error: %v", err)
+	}
+
+	if want := "host1: app errors\nservice 'app' start failed"; body["text"] != want {
+		t.Errorf("slack payload text = %q, want %q", body["text"], want)
+	}
+
+	if authorization != "Bearer token" {
+		t.Errorf("Authorization header = %q, want %q", authorization, "Bearer token")
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid token", http.StatusForbidden)
+	}))
+	defer failing.Close()
+
+	config.URL = failing.URL
+	err := New(config, nil, nil, &logger).Notify(testMessage())
+
+	if _, ok := err.(*ErrNotify); !ok || !strings.Contains(err.Error(), "invalid token") {
+		t.Errorf("Notify() to failing webhook error = %v, want ErrNotify with response", err)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		42*time.Second + 300*time.Millisecond: "42s",
+		90 * time.Minute:                      "1h30m",
+		2*time.Hour + 30*time.Second:          "2h0m",
+	}
+
+	for d, want := range tests {
+		if got := FormatDuration(d); got != want {
+			t.Errorf("FormatDuration(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package notifier
+
+import (
+	"errors"
+
+	"github.com/ashokhin/autosys-nanny/pkg/mailer"
+)
+
+// Smtp sends notifications by email with settings from 'general' section
+type Smtp struct {
+	name      string
+	mailer    *mailer.Mailer
+	templates *Templates
+	to        []string
+}
+
+func NewSmtp(name string, m *mailer.Mailer, t *Templates, to []string) *Smtp {
+	return &Smtp{name: name, mailer: m, templates: t, to: to}
+}
+
+func (n *Smtp) Name() string {
+	return n.name
+}
+
+// Recipients returns emails of notifier
+func (n *Smtp) Recipients() []string {
+	return n.to
+}
+
+func (n *Smtp) Notify(msg *Message) error {
+	if err := n.mailer.CheckSettings(); err != nil {
+		return err
+	}
+
+	// copy of mailer, so headers of shared mailer aren't changed
+	m := *n.mailer
+	headers := *n.mailer.Headers
+	m.Headers = &headers
+
+	m.Headers.To = n.to
+	m.Headers.Subject = msg.Subject
+
+	// both bodies are rendered for multipart emails
+	text, err := n.templates.Text(msg)
+
+	if err != nil {
+		return &ErrTemplate{n.name, err.Error()}
+	}
+
+	html, err := n.templates.Html(msg)
+
+	if err != nil {
+		return &ErrTemplate{n.name, err.Error()}
+	}
+
+	if err := m.SendEmail(text, html, msg.attachments()); err != nil {
+		var errSettings *mailer.ErrBadMailSettings
+
+		if errors.As(err, &errSettings) {
+			return err
+		}
+
+		return &ErrNotify{n.name, err.Error()}
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package notifier
+
+import (
+	"fmt"
+	"log/syslog"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+const SYSLOG_DEFAULT_TAG string = "autosys-nanny"
+
+var syslogFacilities = map[string]syslog.Priority{
+	"kern":     syslog.LOG_KERN,
+	"user":     syslog.LOG_USER,
+	"mail":     syslog.LOG_MAIL,
+	"daemon":   syslog.LOG_DAEMON,
+	"auth":     syslog.LOG_AUTH,
+	"syslog":   syslog.LOG_SYSLOG,
+	"lpr":      syslog.LOG_LPR,
+	"news":     syslog.LOG_NEWS,
+	"uucp":     syslog.LOG_UUCP,
+	"cron":     syslog.LOG_CRON,
+	"authpriv": syslog.LOG_AUTHPRIV,
+	"ftp":      syslog.LOG_FTP,
+	"local0":   syslog.LOG_LOCAL0,
+	"local1":   syslog.LOG_LOCAL1,
+	"local2":   syslog.LOG_LOCAL2,
+	"local3":   syslog.LOG_LOCAL3,
+	"local4":   syslog.LOG_LOCAL4,
+	"local5":   syslog.LOG_LOCAL5,
+	"local6":   syslog.LOG_LOCAL6,
+	"local7":   syslog.LOG_LOCAL7,
+}
+
+func (c *Config) validFacility() bool {
+	if len(c.Facility) == 0 {
+		return true
+	}
+
+	_, ok := syslogFacilities[c.Facility]
+
+	return ok
+}
+
+// Syslog writes every error of notification as separate syslog message
+// into local syslog or into remote one with 'network' and 'address'
+type Syslog struct {
+	config *Config
+	logger *log.Logger
+}
+
+func (n *Syslog) Name() string {
+	return n.config.Name
+}
+
+func (n *Syslog) Notify(msg *Message) error {
+	facility := syslog.LOG_DAEMON
+	tag := SYSLOG_DEFAULT_TAG
+
+	if len(n.config.Facility) > 0 {
+		facility = syslogFacilities[n.config.Facility]
+	}
+
+	if len(n.config.Tag) > 0 {
+		tag = n.config.Tag
+	}
+
+	level.Debug(*n.logger).Log("msg", "write syslog messages", "notifier", n.config.Name,
+		"network", n.config.Network, "address", n.config.Address, "tag", tag)
+
+	w, err := syslog.Dial(n.config.Network, n.config.Address, facility|syslog.LOG_ERR, tag)
+
+	if err != nil {
+		return &ErrNotify{n.config.Name, err.Error()}
+	}
+
+	defer w.Close()
+
+	// messages of digest are written separately
+	for _, m := range msg.messages() {
+		for _, e := range m.ErrorStrings() {
+			if err := w.Err(fmt.Sprintf("%s: %s", m.Subject, e)); err != nil {
+				return &ErrNotify{n.config.Name, err.Error()}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"strings"
+	texttemplate "text/template"
+)
+
+// prefix of template's value which is path to template file
+const TEMPLATE_FILE_PREFIX string = "file:"
+
+// default body of plain text email. Digest has summary table and section per service.
+// Details of service's restart are added after errors of every service.
+const defaultTextTemplate string = `
+{{- define "details" }}
+{{- with .Process }}Process: pid {{ .Pid }}, uptime {{ duration .Uptime }}, RSS {{ bytes .Rss }}, cmdline '{{ .Cmdline }}'
+
+{{ end }}
+{{- with .ExitStatus }}Exit status: {{ . }}
+
+{{ end }}
+{{- with .StopOutput }}Output of stop command:
+{{ join . "\n" }}
+
+{{ end }}
+{{- with .LogTail }}Last lines of log:
+{{ join . "\n" }}
+
+{{ end }}
+{{- with .Diagnostics }}Output of diagnostic command:
+{{ join . "\n" }}
+
+{{ end }}
+{{- with .Attachments }}Attachments:{{ range . }} {{ .Name }}{{ end }}
+
+{{ end }}
+{{- end }}
+{{- if .Digest -}}
+Host '{{ .Hostname }}' got {{ .ErrorsCount }} errors in {{ len .Digest }} notifications
+
+{{ printf "%-40s %-16s %s" "Service" "State" "Errors" }}
+{{ range .Digest }}{{ printf "%-40s %-16s %d" (or .Service "Nanny script") .State (len .Errors) }}
+{{ end }}
+{{- range .Digest }}
+== {{ or .Service "Nanny script" }} ({{ .State }}) ==
+{{ range .ErrorStrings }}{{ . }}
+{{ end }}
+{{ template "details" . }}
+{{- end }}
+{{- else -}}
+{{ range .ErrorStrings }}Host '{{ $.Hostname }}' got error: {{ . }}
+
+{{ end }}
+{{- template "details" . }}
+{{- end }}`
+
+// default body of html email
+const defaultHtmlTemplate string = `
+{{- define "details" }}
+{{- with .Process }}
+		<p>Process: pid {{ .Pid }}, uptime {{ duration .Uptime }}, RSS {{ bytes .Rss }}, cmdline '{{ .Cmdline }}'</p>
+{{- end }}
+{{- with .ExitStatus }}
+		<p>Exit status: {{ . }}</p>
+{{- end }}
+{{- with .StopOutput }}
+		<p>Output of stop command:</p>
+		<pre>{{ join . "\n" }}</pre>
+{{- end }}
+{{- with .LogTail }}
+		<p>Last lines of log:</p>
+		<pre>{{ join . "\n" }}</pre>
+{{- end }}
+{{- with .Diagnostics }}
+		<p>Output of diagnostic command:</p>
+		<pre>{{ join . "\n" }}</pre>
+{{- end }}
+{{- with .Attachments }}
+		<p>Attachments:{{ range . }} {{ .Name }}{{ end }}</p>
+{{- end }}
+{{- end }}
+<html>
+	<head></head>
+	<body>
+{{- if .Digest }}
+		<p>Host '{{ .Hostname }}' got {{ .ErrorsCount }} errors in {{ len .Digest }} notifications</p>
+		<table border="1" cellpadding="4" cellspacing="0">
+			<tr><th>Service</th><th>State</th><th>Errors</th></tr>
+{{- range .Digest }}
+			<tr><td>{{ or .Service "Nanny script" }}</td><td>{{ .State }}</td><td>{{ len .Errors }}</td></tr>
+{{- end }}
+		</table>
+{{- range .Digest }}
+		<h3>{{ or .Service "Nanny script" }} ({{ .State }})</h3>
+{{- range .ErrorStrings }}
+		<br>{{ . }}
+{{- end }}
+{{- template "details" . }}
+{{- end }}
+{{- else }}
+{{- range .ErrorStrings }}
+		<br>Host '{{ $.Hostname }}' got error: {{ . }}
+{{- end }}
+{{- template "details" . }}
+{{- end }}
+	</body>
+</html>`
+
+var templateFuncs = map[string]any{
+	"join":     strings.Join,
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"bytes":    formatBytes,
+	"duration": FormatDuration,
+}
+
+// formatBytes returns size in human-readable binary units, e.g. '12.3MiB'
+func formatBytes(size int64) string {
+	const unit = 1024
+
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+
+	div, exp := int64(unit), 0
+
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f%ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// Templates of subject and bodies of notifications from 'general' section.
+// Subject template is used by all notifiers, body templates are used by emails.
+type Templates struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// templateSource returns template from value or from file if value has 'file:' prefix
+func templateSource(value string) (string, error) {
+	path, ok := strings.CutPrefix(value, TEMPLATE_FILE_PREFIX)
+
+	if !ok {
+		return value, nil
+	}
+
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return "", fmt.Errorf("can't read template file '%s': %w", path, err)
+	}
+
+	return string(content), nil
+}
+
+// NewTemplates parses templates. Empty subject means default subject of notification,
+// empty bodies mean default bodies.
+func NewTemplates(subject string, text string, html string) (*Templates, error) {
+	var err error
+
+	t := new(Templates)
+
+	if len(subject) > 0 {
+		if subject, err = templateSource(subject); err != nil {
+			return nil, err
+		}
+
+		if t.subject, err = texttemplate.New("subject").Funcs(templateFuncs).Parse(subject); err != nil {
+			return nil, fmt.Errorf("can't parse subject template: %w", err)
+		}
+	}
+
+	if len(text) == 0 {
+		text = defaultTextTemplate
+	}
+
+	if text, err = templateSource(text); err != nil {
+		return nil, err
+	}
+
+	if t.text, err = texttemplate.New("text").Funcs(templateFuncs).Parse(text); err != nil {
+		return nil, fmt.Errorf("can't parse text template: %w", err)
+	}
+
+	if len(html) == 0 {
+		html = defaultHtmlTemplate
+	}
+
+	if html, err = templateSource(html); err != nil {
+		return nil, err
+	}
+
+	if t.html, err = htmltemplate.New("html").Funcs(templateFuncs).Parse(html); err != nil {
+		return nil, fmt.Errorf("can't parse html template: %w", err)
+	}
+
+	return t, nil
+}
+
+// Subject returns subject of message rendered by subject template or message's subject without template
+func (t *Templates) Subject(msg *Message) (string, error) {
+	if t.subject == nil {
+		return msg.Subject, nil
+	}
+
+	var buf bytes.Buffer
+
+	if err := t.subject.Execute(&buf, msg); err != nil {
+		return msg.Subject, fmt.Errorf("can't render subject template: %w", err)
+	}
+
+	// subject is single line header
+	return strings.Join(strings.Fields(buf.String()), " "), nil
+}
+
+// Text returns plain text body of message
+func (t *Templates) Text(msg *Message) (string, error) {
+	var buf bytes.Buffer
+
+	if err := t.text.Execute(&buf, msg); err != nil {
+		return "", fmt.Errorf("can't render text template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// Html returns html body of message. Values of message are escaped.
+func (t *Templates) Html(msg *Message) (string, error) {
+	var buf bytes.Buffer
+
+	if err := t.html.Execute(&buf, msg); err != nil {
+		return "", fmt.Errorf("can't render html template: %w", err)
+	}
+
+	return buf.String(), nil
+}
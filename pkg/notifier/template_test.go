@@ -0,0 +1,114 @@
+package notifier
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testDigest() *Message {
+	nannyErr := errors.New("can't read state file")
+	hookErr := errors.New("hook 'pre_start' failed: <exit status 1>")
+
+	return &Message{
+		Hostname: "host1",
+		State:    "digest",
+		Digest: []*Message{
+			testMessage(),
+			{Hostname: "host1", Service: "db", State: "restart-failed", Errors: []*error{&hookErr}},
+			{Hostname: "host1", Errors: []*error{&nannyErr}},
+		},
+	}
+}
+
+func defaultTemplates(t *testing.T) *Templates {
+	templates, err := NewTemplates("", "", "")
+
+	if err != nil {
+		t.Fatalf("NewTemplates() of default templates error: %v", err)
+	}
+
+	return templates
+}
+
+func TestTextBody(t *testing.T) {
+	templates := defaultTemplates(t)
+	body, err := templates.Text(testMessage())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "Host 'host1' got error: service 'app' start failed\n\n"; body != want {
+		t.Errorf("Text() = %q, want %q", body, want)
+	}
+
+	if body, err = templates.Text(testDigest()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"Host 'host1' got 3 errors in 3 notifications",
+		"db                                       restart-failed   1",
+		"== Nanny script () ==\ncan't read state file\n",
+		"== db (restart-failed) ==\nhook 'pre_start' failed: <exit status 1>\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Text() of digest doesn't contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestHtmlBody(t *testing.T) {
+	body, err := defaultTemplates(t).Html(testDigest())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"<tr><td>db</td><td>restart-failed</td><td>1</td></tr>",
+		"<h3>Nanny script ()</h3>",
+		"<br>hook &#39;pre_start&#39; failed: &lt;exit status 1&gt;",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Html() of digest doesn't contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestUserTemplates(t *testing.T) {
+	textFile := filepath.Join(t.TempDir(), "body.tmpl")
+
+	if err := os.WriteFile(textFile, []byte("{{ .Service }}: {{ join .ErrorStrings \"; \" }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := NewTemplates("[{{ upper .State }}]\n{{ .Hostname }} {{ .Service }}", TEMPLATE_FILE_PREFIX+textFile, "")
+
+	if err != nil {
+		t.Fatalf("NewTemplates() unexpected error: %v", err)
+	}
+
+	msg := testMessage()
+	msg.State = "restarted"
+
+	// subject is single line
+	if subject, err := templates.Subject(msg); err != nil || subject != "[RESTARTED] host1 app" {
+		t.Errorf("Subject() = %q, %v, want %q", subject, err, "[RESTARTED] host1 app")
+	}
+
+	if body, err := templates.Text(msg); err != nil || body != "app: service 'app' start failed" {
+		t.Errorf("Text() = %q, %v, want body from template file", body, err)
+	}
+
+	if _, err := NewTemplates("{{ .Service ", "", ""); err == nil {
+		t.Error("NewTemplates() with invalid subject template returns no error")
+	}
+
+	if _, err := NewTemplates("", TEMPLATE_FILE_PREFIX+filepath.Join(t.TempDir(), "missing.tmpl"), ""); err == nil {
+		t.Error("NewTemplates() with missing template file returns no error")
+	}
+}
@@ -0,0 +1,149 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+const (
+	WEBHOOK_FORMAT_JSON       string        = "json"
+	WEBHOOK_FORMAT_SLACK      string        = "slack"
+	WEBHOOK_FORMAT_MATTERMOST string        = "mattermost"
+	WEBHOOK_FORMAT_TEAMS      string        = "teams"
+	WEBHOOK_DEFAULT_TIMEOUT   time.Duration = 10 * time.Second
+	// maximum length of response body in error
+	WEBHOOK_MAX_RESPONSE int64 = 512
+)
+
+var webhookFormats = []string{WEBHOOK_FORMAT_JSON, WEBHOOK_FORMAT_SLACK, WEBHOOK_FORMAT_MATTERMOST, WEBHOOK_FORMAT_TEAMS}
+
+func (c *Config) webhookFormat() string {
+	if len(c.Format) == 0 {
+		return WEBHOOK_FORMAT_JSON
+	}
+
+	return c.Format
+}
+
+// Webhook posts notifications as JSON to URL
+type Webhook struct {
+	config *Config
+	logger *log.Logger
+}
+
+func (n *Webhook) Name() string {
+	return n.config.Name
+}
+
+// jsonMessage is representation of message in 'json' webhook format and in JSONL file
+type jsonMessage struct {
+	Time     time.Time `json:"time"`
+	Hostname string    `json:"hostname"`
+	Service  string    `json:"service,omitempty"`
+	State    string    `json:"state,omitempty"`
+	Subject  string    `json:"subject"`
+	Errors   []string  `json:"errors"`
+	// messages grouped into digest
+	Messages []*jsonMessage `json:"messages,omitempty"`
+}
+
+func newJsonMessage(msg *Message) *jsonMessage {
+	jm := &jsonMessage{
+		Time:     msg.Time,
+		Hostname: msg.Hostname,
+		Service:  msg.Service,
+		State:    msg.State,
+		Subject:  msg.Subject,
+		Errors:   msg.ErrorStrings(),
+	}
+
+	for _, dm := range msg.Digest {
+		jm.Messages = append(jm.Messages, newJsonMessage(dm))
+	}
+
+	return jm
+}
+
+// errors of message for chat webhooks. Messages of digest are separated by empty lines
+func messageText(msg *Message, lineBreak string) string {
+	lines := msg.ErrorStrings()
+
+	for _, dm := range msg.Digest {
+		lines = append(lines, "", dm.Subject)
+		lines = append(lines, dm.ErrorStrings()...)
+	}
+
+	return strings.Join(lines, lineBreak)
+}
+
+// payload returns request body in notifier's format
+func (n *Webhook) payload(msg *Message) any {
+	switch n.config.webhookFormat() {
+	case WEBHOOK_FORMAT_SLACK, WEBHOOK_FORMAT_MATTERMOST:
+		return map[string]string{"text": fmt.Sprintf("%s\n%s", msg.Subject, messageText(msg, "\n"))}
+	case WEBHOOK_FORMAT_TEAMS:
+		return map[string]string{
+			"@type":    "MessageCard",
+			"@context": "http://schema.org/extensions",
+			"summary":  msg.Subject,
+			"title":    msg.Subject,
+			// Teams renders text as markdown, so line breaks should be doubled
+			"text": messageText(msg, "\n\n"),
+		}
+	default:
+		return newJsonMessage(msg)
+	}
+}
+
+func (n *Webhook) Notify(msg *Message) error {
+	body, err := json.Marshal(n.payload(msg))
+
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.config.URL, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for key, value := range n.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	timeout := n.config.Timeout
+
+	if timeout <= 0 {
+		timeout = WEBHOOK_DEFAULT_TIMEOUT
+	}
+
+	level.Debug(*n.logger).Log("msg", "post webhook", "notifier", n.config.Name,
+		"format", n.config.webhookFormat(), "value", string(body))
+
+	resp, err := (&http.Client{Timeout: timeout}).Do(req)
+
+	if err != nil {
+		return &ErrNotify{n.config.Name, err.Error()}
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, WEBHOOK_MAX_RESPONSE))
+
+		return &ErrNotify{n.config.Name, fmt.Sprintf("webhook returned '%s': %s", resp.Status, strings.TrimSpace(string(respBody)))}
+	}
+
+	return nil
+}